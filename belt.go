@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"io"
+	"math"
+	math_rand "math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Mean motion resonances with Jupiter that open Kirkwood gaps, p:q being
+// the ratio of Jupiter's orbital period to the asteroid's
+var kirkwoodResonances = []struct{ p, q int }{{3, 1}, {5, 2}, {7, 3}, {2, 1}}
+
+// Semi-major axis of the p:q resonance with a planet at a
+func resonanceRadius(a float64, p, q int) float64 {
+	return a * math.Pow(float64(q)/float64(p), 2.0/3.0)
+}
+
+// Parse the inner and outer edge of a belt, "inner,outer" in au
+func parseBelt(spec string) (float64, float64, error) {
+	parts := strings.Split(spec, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("belt %q is not inner,outer", spec)
+	}
+	inner, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	outer, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if inner <= 0 || outer <= inner {
+		return 0, 0, fmt.Errorf("belt needs 0 < inner < outer")
+	}
+	return inner * au, outer * au, nil
+}
+
+// The Sun and Jupiter with n test particles between inner and outer,
+// uniform in semi-major axis, with eccentricities and inclinations drawn
+// from spread like in random MODE.
+func asteroidBelt(w, h, n int, inner, outer float64, spread orbitSpread, rng *math_rand.Rand) *World {
+	world := &World{
+		scale:   1.0,
+		spt:     20,
+		running: true,
+		elapsed: 0,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+	}
+	sun := body.NewBody("Sol", 0, 0, solarBodies[0].radius, solarBodies[0].mass, 0, 0, sprites["sun"])
+	var jupiter *body.Body
+	for _, sb := range solarBodies {
+		if sb.name == "Jupiter" {
+			jupiter = body.NewOrbitingBody(sb.name, sun, sb.elements(), sb.radius, sb.mass, sprites["jupiter"])
+		}
+	}
+	world.bodies = []*body.Body{sun, jupiter}
+	world.fitToScreen()
+	for i := 0; i < n; i++ {
+		a := inner + rng.Float64()*(outer-inner)
+		world.bodies = append(world.bodies, body.NewOrbitingTestParticle(fmt.Sprintf("A%v", i), sun,
+			spread.elements(a, rng), MinRadius*world.mpp, sprites["circle"]))
+	}
+
+	innerOrbit := body.Elements{A: inner}
+	world.dt = innerOrbit.Period(G*sun.Mass) / 200
+	fmt.Printf("%v\n%v\n", sun, jupiter)
+	fmt.Printf("Belt of %v test particles from %.3g to %.3g au\n", n, inner/au, outer/au)
+	aj := body.ElementsOf(jupiter.Pos, jupiter.Vel, G*(sun.Mass+jupiter.Mass)).A
+	for _, r := range kirkwoodResonances {
+		fmt.Printf("%v:%v resonance with Jupiter at %.3f au\n", r.p, r.q, resonanceRadius(aj, r.p, r.q)/au)
+	}
+	return world
+}
+
+// Histogram of the osculating semi-major axes of the test particles around
+// the heaviest body, accumulated over the run so resonance gaps stand out
+type smaHistogram struct {
+	center *body.Body
+	lo     float64
+	hi     float64
+	counts []int
+	// simulated seconds between samples
+	every   float64
+	next    float64
+	samples int
+	// the second heaviest body, resonances with it are labeled
+	perturber *body.Body
+}
+
+// Histogram with bins between lo and hi sampled every so many simulated
+// seconds
+func newSMAHistogram(w *World, lo, hi float64, bins int, every float64) *smaHistogram {
+	center, perturber := w.heaviestPair()
+	return &smaHistogram{center: center, perturber: perturber, lo: lo, hi: hi, counts: make([]int, bins), every: every}
+}
+
+// Bin the bound test particles if a sample is due
+func (hg *smaHistogram) sample(w *World) {
+	if w.elapsed < hg.next {
+		return
+	}
+	hg.next = w.elapsed + hg.every
+	hg.samples++
+	mu := G * hg.center.Mass
+	for _, b := range w.bodies {
+		if !b.TestParticle {
+			continue
+		}
+		el := body.ElementsOf(vector.Sub(b.Pos, hg.center.Pos), vector.Sub(b.Vel, hg.center.Vel), mu)
+		if el.E >= 1 || el.A < hg.lo || el.A >= hg.hi {
+			continue
+		}
+		hg.counts[hg.bin(el.A)]++
+	}
+}
+
+// Bin of a semi-major axis from lo up to hi
+func (hg *smaHistogram) bin(a float64) int {
+	// Rounding can put a just below hi past the last bin
+	return min(int((a-hg.lo)/(hg.hi-hg.lo)*float64(len(hg.counts))), len(hg.counts)-1)
+}
+
+// Write the histogram as CSV, one row per bin with the edges in au, the
+// count summed over all samples and the resonances inside the bin
+func (hg smaHistogram) write(out io.Writer) error {
+	var aj float64
+	if hg.perturber != nil {
+		aj = body.ElementsOf(vector.Sub(hg.perturber.Pos, hg.center.Pos), vector.Sub(hg.perturber.Vel, hg.center.Vel),
+			G*(hg.center.Mass+hg.perturber.Mass)).A
+	}
+	if _, err := fmt.Fprintln(out, "a_min_au,a_max_au,count,resonance"); err != nil {
+		return err
+	}
+	width := (hg.hi - hg.lo) / float64(len(hg.counts))
+	for i, c := range hg.counts {
+		lo, hi := hg.lo+float64(i)*width, hg.lo+float64(i+1)*width
+		var labels []string
+		for _, r := range kirkwoodResonances {
+			if a := resonanceRadius(aj, r.p, r.q); aj > 0 && a >= lo && a < hi {
+				labels = append(labels, fmt.Sprintf("%v:%v", r.p, r.q))
+			}
+		}
+		if _, err := fmt.Fprintf(out, "%.4f,%.4f,%v,%v\n", lo/au, hi/au, c, strings.Join(labels, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the semi-major axis histogram to path as CSV
+func (w World) exportHistogram(path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Unable to write histogram: %v\n", err)
+		return
+	}
+	defer file.Close()
+	if err := w.histogram.write(file); err != nil {
+		fmt.Printf("Unable to write histogram: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote %v samples of the semi-major axes to %v\n", w.histogram.samples, path)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestAsteroidBelt(t *testing.T) {
+	inner, outer, err := parseBelt("2,3.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resetSprites()
+	world := asteroidBelt(1024, 1024, 300, inner, outer, orbitSpread{0.1, 0.1}, newRand(1))
+	if len(world.bodies) != 302 || world.bodies[1].Name != "Jupiter" {
+		t.Fatalf("expected the Sun, Jupiter and 300 asteroids")
+	}
+	// The 3:1 Kirkwood gap
+	aj := body.ElementsOf(world.bodies[1].Pos, world.bodies[1].Vel, G*(world.bodies[0].Mass+world.bodies[1].Mass)).A
+	if a := resonanceRadius(aj, 3, 1) / au; math.Abs(a-2.50) > 0.01 {
+		t.Errorf("3:1 resonance at %v au", a)
+	}
+
+	world.histogram = newSMAHistogram(world, inner, outer, 30, 10*world.timestep())
+	for i := 0; i < 3; i++ {
+		world.tick()
+	}
+	hg := world.histogram
+	if hg.center != world.bodies[0] || hg.perturber != world.bodies[1] {
+		t.Errorf("histogram should be around the Sun with Jupiter as perturber")
+	}
+	total := 0
+	for _, c := range hg.counts {
+		total += c
+	}
+	// Asteroids start inside the belt, and only those near the edges can be
+	// perturbed out of it over a few steps
+	if hg.samples < 2 || total > 300*hg.samples || total < 290*hg.samples {
+		t.Errorf("%v samples of %v asteroids binned %v times", hg.samples, 300, total)
+	}
+	var out strings.Builder
+	if err := hg.write(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 31 || lines[0] != "a_min_au,a_max_au,count,resonance" || !strings.Contains(out.String(), ",3:1\n") {
+		t.Errorf("unexpected histogram:\n%v", out.String())
+	}
+
+	// Edges where a just below hi rounds to the end of the last bin
+	edges := &smaHistogram{lo: 1.7365584472313276, hi: 7.662795979355783, counts: make([]int, 94)}
+	if i := edges.bin(math.Nextafter(edges.hi, 0)); i != 93 {
+		t.Errorf("a just below hi should be in the last bin: %v", i)
+	}
+	if i := edges.bin(edges.lo); i != 0 {
+		t.Errorf("lo should be in the first bin: %v", i)
+	}
+
+	for _, bad := range []string{"2", "3,2", "a,b"} {
+		if _, _, err := parseBelt(bad); err == nil {
+			t.Errorf("%q should be an error", bad)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	math_rand "math/rand"
+	"strconv"
+	"strings"
+)
+
+// Stars of a binary and the orbit of the secondary around the primary
+type binarySpec struct {
+	// mass of the primary in kg, the secondary has massRatio times it
+	mass      float64
+	massRatio float64
+	// semi-major axis in meters and eccentricity of the binary orbit
+	separation float64
+	ecc        float64
+	planets    []binaryPlanet
+}
+
+// Planet of a binary: host is "AB" for a circumbinary (P-type) orbit and
+// "A" or "B" for an orbit around one star (S-type)
+type binaryPlanet struct {
+	host string
+	a    float64
+}
+
+// Parse a comma separated planet list like "AB:2,A:0.15", semi-major axes
+// in au
+func parseBinaryPlanets(spec string) ([]binaryPlanet, error) {
+	var planets []binaryPlanet
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		host, a, ok := strings.Cut(p, ":")
+		if !ok {
+			return nil, fmt.Errorf("planet %q is not host:a", p)
+		}
+		host = strings.ToUpper(strings.TrimSpace(host))
+		if host != "AB" && host != "A" && host != "B" {
+			return nil, fmt.Errorf("planet %q host must be AB, A or B", p)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(a), 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("planet %q needs a positive semi-major axis", p)
+		}
+		planets = append(planets, binaryPlanet{host, v * au})
+	}
+	return planets, nil
+}
+
+// Critical semi-major axis in units of the binary's from the fits of
+// Holman and Wiegert (1999). For S-type orbits mu is the mass fraction of
+// the star the planet does not orbit, inside this the orbit is stable; for
+// P-type orbits it is the mass fraction of the secondary, outside this the
+// orbit is stable.
+func holmanWiegert(circumbinary bool, mu, e float64) float64 {
+	if circumbinary {
+		return 1.60 + 5.10*e - 2.22*e*e + 4.12*mu - 4.27*e*mu - 5.09*mu*mu + 4.61*e*e*mu*mu
+	}
+	return 0.464 - 0.380*mu - 0.631*e + 0.586*mu*e + 0.150*e*e - 0.198*mu*e*e
+}
+
+// Two stars, A and B starting at periastron, with Earth mass planets on
+// circular orbits at random phases. Prints each planet's Holman-Wiegert
+// stability limit.
+func binarySystem(w, h int, spec binarySpec, rng *math_rand.Rand) (*World, error) {
+	if spec.massRatio <= 0 || spec.massRatio > 1 {
+		return nil, fmt.Errorf("mass ratio must be in (0, 1]")
+	}
+	if spec.ecc < 0 || spec.ecc >= 1 {
+		return nil, fmt.Errorf("binary eccentricity must be in [0, 1)")
+	}
+	world := &World{
+		scale:   1.0,
+		spt:     10,
+		running: true,
+		elapsed: 0,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+	}
+	sun := solarBodies[0]
+	a := body.NewBody("A", 0, 0, sun.radius, spec.mass, 0, 0, sprites["sun"])
+	orbit := body.Elements{A: spec.separation, E: spec.ecc}
+	b := body.NewOrbitingBody("B", a, orbit, sun.radius*math.Cbrt(spec.massRatio), spec.mass*spec.massRatio,
+		sprites["sun"])
+	world.bodies = []*body.Body{a, b}
+	m := a.Mass + b.Mass
+	// The pair as one body at its barycenter, for circumbinary orbits
+	ab := body.NewBodyVector("AB",
+		vector.DivScalar(vector.Add(vector.MultScalar(a.Pos, a.Mass), vector.MultScalar(b.Pos, b.Mass)), m),
+		vector.DivScalar(vector.Add(vector.MultScalar(a.Vel, a.Mass), vector.MultScalar(b.Vel, b.Mass)), m),
+		0, m, nil)
+	fmt.Printf("%v\n%v\n", a, b)
+	fmt.Printf("Binary mass ratio %.3g, a %.4g au, e %.3g, period %.4g days\n",
+		spec.massRatio, spec.separation/au, spec.ecc, orbit.Period(G*m)/86400)
+
+	// Planets get the Earth's mass and radius
+	earth := solarBodies[3]
+	dt := orbit.Period(G*m) / 1000
+	count := map[string]int{}
+	for _, p := range spec.planets {
+		host, mu, circumbinary := ab, b.Mass/m, true
+		if p.host == "A" {
+			host, circumbinary = a, false
+		} else if p.host == "B" {
+			host, mu, circumbinary = b, a.Mass/m, false
+		}
+		count[p.host]++
+		el := body.Elements{A: p.a, M: rng.Float64() * math.Pi * 2}
+		planet := body.NewOrbitingBody(fmt.Sprintf("%v%v", p.host, count[p.host]), host, el, earth.radius, earth.mass,
+			randomPlanetSprite(rng))
+		world.bodies = append(world.bodies, planet)
+		dt = math.Min(dt, el.Period(G*(host.Mass+planet.Mass))/200)
+
+		limit := holmanWiegert(circumbinary, mu, spec.ecc) * spec.separation
+		stable := p.a < limit
+		kind, side := "S-type", "inside"
+		if circumbinary {
+			stable = p.a > limit
+			kind, side = "P-type", "outside"
+		}
+		verdict := "stable"
+		if !stable {
+			verdict = "UNSTABLE"
+		}
+		fmt.Printf("%v: %v a %.4g au, stable %v %.4g au: %v\n", planet.Name, kind, p.a/au, side, limit/au, verdict)
+	}
+
+	world.dt = dt
+	world.fitToScreen()
+	return world, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBinarySystem(t *testing.T) {
+	// Values from Table 3 and 7 of Holman and Wiegert (1999)
+	if a := holmanWiegert(false, 0.5, 0); math.Abs(a-0.274) > 1e-9 {
+		t.Errorf("S-type limit for equal masses on a circular orbit is %v", a)
+	}
+	if a := holmanWiegert(true, 0.5, 0); math.Abs(a-2.3875) > 1e-9 {
+		t.Errorf("P-type limit for equal masses on a circular orbit is %v", a)
+	}
+
+	planets, err := parseBinaryPlanets("AB:3,a:0.1,B:0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resetSprites()
+	spec := binarySpec{mass: 2e30, massRatio: 0.5, separation: au, ecc: 0.2, planets: planets}
+	world, err := binarySystem(1024, 1024, spec, newRand(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, b := range world.bodies {
+		names = append(names, b.Name)
+	}
+	if strings.Join(names, ",") != "A,B,AB1,A1,B1" {
+		t.Fatalf("unexpected bodies %v", names)
+	}
+	a, b := world.bodies[0], world.bodies[1]
+	if d := a.Pos.DistanceTo(b.Pos); math.Abs(d-0.8*au) > 1 {
+		t.Errorf("stars should start at periastron, %v au apart", d/au)
+	}
+
+	// The S-type planet well inside the limit stays with its star for a
+	// binary orbit
+	steps := int(body.Elements{A: au}.Period(G*3e30) / world.dt)
+	for i := 0; i < steps; i++ {
+		world.tick()
+	}
+	if len(world.bodies) != 5 {
+		t.Fatalf("bodies were lost: %v", len(world.bodies))
+	}
+	if d := world.bodies[3].Pos.DistanceTo(world.bodies[0].Pos); d < 0.08*au || d > 0.12*au {
+		t.Errorf("A1 left its star, now %v au away", d/au)
+	}
+
+	for _, bad := range []string{"C:1", "AB", "A:-1"} {
+		if _, err := parseBinaryPlanets(bad); err == nil {
+			t.Errorf("%q should be an error", bad)
+		}
+	}
+	if _, err := binarySystem(1024, 1024, binarySpec{mass: 2e30, massRatio: 2, separation: au}, newRand(1)); err == nil {
+		t.Errorf("mass ratio above 1 should be an error")
+	}
+}
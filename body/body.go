@@ -5,12 +5,31 @@ import (
 	"github.com/faiface/pixel"
 	"github.com/seifertd/go/vector"
 	"math"
+	"sync/atomic"
 )
 
 const G = 6.674e-11
 
+var lastId uint64
+
+// Next unused body identifier
+func NewId() uint64 {
+	return atomic.AddUint64(&lastId, 1)
+}
+
+// Make sure NewId never hands out id, e.g. for bodies restored from a file
+func ReserveId(id uint64) {
+	for {
+		last := atomic.LoadUint64(&lastId)
+		if last >= id || atomic.CompareAndSwapUint64(&lastId, last, id) {
+			return
+		}
+	}
+}
+
 type Body struct {
-	Id      string
+	// Unique and never changes, even when the body absorbs others
+	Id      uint64
 	Name    string
 	Pos     vector.Vector
 	Vel     vector.Vector
@@ -19,29 +38,40 @@ type Body struct {
 	Mass    float64
 	AccChan chan vector.Vector
 	Sprite  *pixel.Sprite
+	// Test particles feel the gravity of massive bodies but exert none
+	TestParticle bool
+	// Electric charge in coulombs, only used by the Coulomb force law
+	Charge float64
+	// Spin angular momentum in kg m^2/s
+	Spin vector.Vector
 }
 
 func NewBody(name string, x float64, y float64, r float64, m float64,
 	vx float64, vy float64, s *pixel.Sprite) *Body {
-	return &Body{name, name, vector.New2DVector(x, y), vector.New2DVector(vx, vy),
-		vector.New2DVector(0, 0), r, m, make(chan vector.Vector), s}
+	return &Body{NewId(), name, vector.New2DVector(x, y), vector.New2DVector(vx, vy),
+		vector.New2DVector(0, 0), r, m, make(chan vector.Vector), s, false, 0, vector.Vector{}}
 }
 func NewBodyVector(name string, pos vector.Vector, vel vector.Vector,
 	r float64, m float64, s *pixel.Sprite) *Body {
-	return &Body{name, name, pos, vel, vector.New2DVector(0, 0),
-		r, m, make(chan vector.Vector), s}
+	return &Body{NewId(), name, pos, vel, vector.New2DVector(0, 0),
+		r, m, make(chan vector.Vector), s, false, 0, vector.Vector{}}
+}
+func NewTestParticle(name string, pos vector.Vector, vel vector.Vector,
+	r float64, s *pixel.Sprite) *Body {
+	return &Body{NewId(), name, pos, vel, vector.New2DVector(0, 0),
+		r, 0, make(chan vector.Vector), s, true, 0, vector.Vector{}}
 }
 
 func (b Body) String() string {
 	//return b.Name
-	return fmt.Sprintf("BODY: %v: m:%v vel:%v,%v pos:%v,%v r:%v",
-		b.Name, b.Mass, b.Vel.X, b.Vel.Y, b.Pos.X, b.Pos.Y, b.Radius)
+	return fmt.Sprintf("BODY: %v (#%v): m:%v vel:%v,%v pos:%v,%v r:%v",
+		b.Name, b.Id, b.Mass, b.Vel.X, b.Vel.Y, b.Pos.X, b.Pos.Y, b.Radius)
 }
 
 func (b *Body) CalculateAcceleration(others []*Body) {
 	deltaA := vector.Vector{0, 0, 0}
 	for _, body2 := range others {
-		if b == body2 {
+		if b == body2 || body2.TestParticle {
 			continue
 		}
 		d := math.Sqrt(math.Pow(b.Pos.X-body2.Pos.X, 2) + math.Pow(b.Pos.Y-body2.Pos.Y, 2))
@@ -58,20 +88,61 @@ func (b Body) Collides(other *Body) bool {
 	}
 	dx := b.Pos.X - other.Pos.X
 	dy := b.Pos.Y - other.Pos.Y
+	dz := b.Pos.Z - other.Pos.Z
 	r2 := b.Radius + other.Radius
-	return dx*dx+dy*dy-r2*r2 <= 0
+	return dx*dx+dy*dy+dz*dz-r2*r2 <= 0
+}
+
+// Cross product a × b
+func Cross(a, b vector.Vector) vector.Vector {
+	return vector.Vector{a.Y*b.Z - a.Z*b.Y, a.Z*b.X - a.X*b.Z, a.X*b.Y - a.Y*b.X}
+}
+
+// Moment of inertia treating the body as a uniform sphere
+func (b Body) MomentOfInertia() float64 {
+	return 0.4 * b.Mass * b.Radius * b.Radius
+}
+
+// Angular velocity of the body's rotation in rad/s
+func (b Body) AngularVelocity() vector.Vector {
+	i := b.MomentOfInertia()
+	if i == 0 {
+		return vector.Vector{0, 0, 0}
+	}
+	return vector.DivScalar(b.Spin, i)
+}
+
+// Rotation period in seconds, +Inf for a body that does not spin
+func (b Body) SpinPeriod() float64 {
+	w := b.AngularVelocity().Magnitude()
+	if w == 0 {
+		return math.Inf(1)
+	}
+	return 2 * math.Pi / w
+}
+
+// Set the spin about the z axis from a rotation period in seconds. A
+// negative period is a retrograde rotation.
+func (b *Body) SetSpinPeriod(period float64) {
+	b.Spin = vector.Vector{0, 0, 2 * math.Pi / period * b.MomentOfInertia()}
 }
 
 func (b *Body) CollideWith(other *Body) {
 	// Assume other is going away
 	nr := math.Pow(math.Pow(b.Radius, 3)+math.Pow(other.Radius, 3), 1.0/3.0)
-	vnx := (b.Mass*b.Vel.X + other.Mass*other.Vel.X) /
-		(b.Mass + other.Mass)
-	vny := (b.Mass*b.Vel.Y + other.Mass*other.Vel.Y) /
-		(b.Mass + other.Mass)
+	m := b.Mass + other.Mass
+	if m > 0 {
+		// The merged body sits at the pair's center of mass and the orbital
+		// angular momentum of the pair about it becomes spin
+		mu := b.Mass * other.Mass / m
+		relPos := vector.Sub(other.Pos, b.Pos)
+		relVel := vector.Sub(other.Vel, b.Vel)
+		b.Spin.Add(other.Spin)
+		b.Spin.Add(vector.MultScalar(Cross(relPos, relVel), mu))
+		b.Pos = vector.DivScalar(vector.Add(vector.MultScalar(b.Pos, b.Mass), vector.MultScalar(other.Pos, other.Mass)), m)
+		b.Vel = vector.DivScalar(vector.Add(vector.MultScalar(b.Vel, b.Mass), vector.MultScalar(other.Vel, other.Mass)), m)
+	}
 	b.Radius = nr
-	b.Mass += other.Mass
-	b.Vel.X = vnx
-	b.Vel.Y = vny
-	b.Name = fmt.Sprintf("%v<-%v", b.Name, other.Name)
+	b.Mass = m
+	b.Charge += other.Charge
 }
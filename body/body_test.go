@@ -1,6 +1,8 @@
 package body
 
 import (
+	"github.com/seifertd/go/vector"
+	"math"
 	"testing"
 )
 
@@ -18,14 +20,24 @@ func TestBodyCollisions(t *testing.T) {
 	if b1.Vel.Y != (5.0 / 3.0) {
 		t.Errorf("b2 should conserve momentum in x dir: %v != %v", b1.Vel.Y, 5.0/3.0)
 	}
-	if b1.Name != "b1<-b2" {
-		t.Errorf("b2's name should incorporate b1: %v", b1.Name)
+	if b1.Name != "b1" {
+		t.Errorf("b1 should keep its name: %v", b1.Name)
 	}
 	if b1.Radius <= oldRadius {
 		t.Errorf("b1's radius should increase %v < %v", oldRadius, b1.Radius)
 	}
 }
 
+func TestCollisionConservesCharge(t *testing.T) {
+	b1 := NewBody("b1", 0, 0, 10, 10, 0, 0, nil)
+	b2 := NewBody("b2", 5, 0, 5, 5, 0, 0, nil)
+	b1.Charge, b2.Charge = 3, -1
+	b1.CollideWith(b2)
+	if b1.Charge != 2 {
+		t.Errorf("merged charge should be the sum of both: %v", b1.Charge)
+	}
+}
+
 func TestBodyCollisionTesting(t *testing.T) {
 	b1 := NewBody("b1", 0, 0, 10, 20, 0, 0, nil)
 	b2 := NewBody("b2", 100, 100, 10, 20, 0, 0, nil)
@@ -46,3 +58,81 @@ func TestBodyCollisionTesting(t *testing.T) {
 		t.Errorf("b1 and b3 should be colliding")
 	}
 }
+
+func TestCollisionsAreThreeDimensional(t *testing.T) {
+	b1 := NewBodyVector("b1", vector.Vector{0, 0, 0}, vector.Vector{}, 10, 20, nil)
+	b2 := NewBodyVector("b2", vector.Vector{5, 5, 100}, vector.Vector{}, 10, 20, nil)
+	if b1.Collides(b2) {
+		t.Errorf("bodies far apart in z should not collide")
+	}
+	b2.Pos.Z = 15
+	if !b1.Collides(b2) {
+		t.Errorf("bodies overlapping in 3D should collide")
+	}
+}
+
+func TestTestParticlesExertNoGravity(t *testing.T) {
+	sun := NewBody("sun", 0, 0, 10, 1e30, 0, 0, nil)
+	tp := NewTestParticle("tp", vector.New2DVector(1e9, 0), vector.New2DVector(0, 0), 1, nil)
+	others := []*Body{sun, tp}
+
+	go sun.CalculateAcceleration(others)
+	if acc := <-sun.AccChan; acc.Magnitude() != 0 {
+		t.Errorf("test particle should not pull on the sun: %v", acc)
+	}
+	go tp.CalculateAcceleration(others)
+	if acc := <-tp.AccChan; acc.X >= 0 {
+		t.Errorf("test particle should be pulled toward the sun: %v", acc)
+	}
+}
+
+func TestCollisionConservesAngularMomentum(t *testing.T) {
+	b1 := NewBody("b1", 10, 0, 10, 30, 0, 2, nil)
+	b2 := NewBody("b2", -5, 3, 5, 10, 1, -4, nil)
+	b2.Spin = vector.Vector{0, 0, 7}
+	total := func(bodies ...*Body) vector.Vector {
+		l := vector.Vector{0, 0, 0}
+		for _, b := range bodies {
+			l.Add(vector.MultScalar(Cross(b.Pos, b.Vel), b.Mass))
+			l.Add(b.Spin)
+		}
+		return l
+	}
+	before := total(b1, b2)
+	b1.CollideWith(b2)
+	after := total(b1)
+	if vector.Sub(before, after).Magnitude() > 1e-9 {
+		t.Errorf("angular momentum should be conserved: %v != %v", before, after)
+	}
+	if b1.SpinPeriod() <= 0 || math.IsInf(b1.SpinPeriod(), 1) {
+		t.Errorf("merged body should spin: %v", b1.SpinPeriod())
+	}
+}
+
+func TestSpinPeriod(t *testing.T) {
+	b := NewBody("b", 0, 0, 6_371_000, 5.9724e24, 0, 0, nil)
+	if !math.IsInf(b.SpinPeriod(), 1) {
+		t.Errorf("new body should not spin: %v", b.SpinPeriod())
+	}
+	b.SetSpinPeriod(86164)
+	if math.Abs(b.SpinPeriod()-86164) > 1e-6 {
+		t.Errorf("spin period should round trip: %v", b.SpinPeriod())
+	}
+}
+
+func TestBodyIdsAreUnique(t *testing.T) {
+	b1 := NewBody("b1", 0, 0, 10, 10, 0, 0, nil)
+	b2 := NewBody("b1", 0, 0, 10, 10, 0, 0, nil)
+	if b1.Id == b2.Id || b1.Id == 0 {
+		t.Errorf("bodies should get unique non zero ids: %v %v", b1.Id, b2.Id)
+	}
+	id := b1.Id
+	b1.CollideWith(b2)
+	if b1.Id != id {
+		t.Errorf("merging should not change a body's id: %v != %v", b1.Id, id)
+	}
+	ReserveId(b2.Id + 100)
+	if b3 := NewBody("b3", 0, 0, 1, 1, 0, 0, nil); b3.Id <= b2.Id+100 {
+		t.Errorf("reserved ids should not be reused: %v", b3.Id)
+	}
+}
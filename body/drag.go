@@ -0,0 +1,41 @@
+package body
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"math"
+)
+
+// GasDrag is the drag of a gas disk around Star on the other bodies. The
+// gas moves on circular orbits in the xy plane, slower than Keplerian by
+// the fraction Eta because of its pressure support, and pulls each body's
+// velocity toward its own over the stopping time. As in the Epstein regime
+// the stopping time is proportional to the body's radius, Tau seconds per
+// meter.
+type GasDrag struct {
+	Star *Body
+	Eta  float64
+	Tau  float64
+}
+
+// Velocity of the gas at pos
+func (g GasDrag) GasVelocity(pos vector.Vector) vector.Vector {
+	d := vector.Sub(pos, g.Star.Pos)
+	r := math.Hypot(d.X, d.Y)
+	if r == 0 {
+		return g.Star.Vel
+	}
+	v := (1 - g.Eta) * math.Sqrt(G*g.Star.Mass/r)
+	return vector.Add(g.Star.Vel, vector.Vector{-v * d.Y / r, v * d.X / r, 0})
+}
+
+func (g GasDrag) Acceleration(b *Body) vector.Vector {
+	if b == g.Star || b.Radius <= 0 {
+		return vector.Vector{0, 0, 0}
+	}
+	return vector.DivScalar(vector.Sub(g.GasVelocity(b.Pos), b.Vel), g.Tau*b.Radius)
+}
+
+func (g GasDrag) String() string {
+	return fmt.Sprintf("gas drag around %v eta:%v tau:%vs/m", g.Star.Name, g.Eta, g.Tau)
+}
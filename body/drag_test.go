@@ -0,0 +1,30 @@
+package body
+
+import (
+	"github.com/seifertd/go/vector"
+	"math"
+	"testing"
+)
+
+func TestGasDrag(t *testing.T) {
+	star := NewBody("star", 0, 0, 7e8, 2e30, 0, 0, nil)
+	drag := GasDrag{Star: star, Eta: 0.01, Tau: 1e6}
+	vk := math.Sqrt(G * star.Mass / 1.5e11)
+	gas := drag.GasVelocity(vector.Vector{1.5e11, 0, 1e9})
+	if math.Abs(gas.Y-0.99*vk) > 1e-9*vk || gas.X != 0 || gas.Z != 0 {
+		t.Errorf("gas should orbit at 0.99 of the Keplerian speed: %v", gas)
+	}
+
+	b := NewBody("b", 1.5e11, 0, 1e3, 1e15, 0, gas.Y, nil)
+	if a := drag.Acceleration(b); a.Magnitude() > 1e-12 {
+		t.Errorf("a body moving with the gas feels no drag: %v", a)
+	}
+	b.Vel.Y = vk
+	a := drag.Acceleration(b)
+	if want := -0.01 * vk / 1e9; math.Abs(a.Y-want) > 1e-9*math.Abs(want) {
+		t.Errorf("drag should slow the body over its stopping time: %v != %v", a.Y, want)
+	}
+	if a := drag.Acceleration(star); a.Magnitude() != 0 {
+		t.Errorf("the star feels no drag from its own disk: %v", a)
+	}
+}
@@ -0,0 +1,134 @@
+package body
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"math"
+)
+
+// Coulomb's constant in N m^2 / C^2
+const Ke = 8.9875517923e9
+
+// ForceLaw is the pairwise interaction between bodies. Acceleration is the
+// acceleration of b due to source and Energy is the potential energy of b
+// in the field of source.
+type ForceLaw interface {
+	Acceleration(b, source *Body) vector.Vector
+	Energy(b, source *Body) float64
+	String() string
+}
+
+// Separation vector from b to source and its length
+func separation(b, source *Body) (vector.Vector, float64) {
+	d := vector.Sub(source.Pos, b.Pos)
+	return d, d.Magnitude()
+}
+
+// Newtonian is plain inverse square gravity.
+type Newtonian struct{}
+
+func (Newtonian) Acceleration(b, source *Body) vector.Vector {
+	d, r := separation(b, source)
+	return vector.MultScalar(d, G*source.Mass/(r*r*r))
+}
+
+func (Newtonian) Energy(b, source *Body) float64 {
+	_, r := separation(b, source)
+	return -G * b.Mass * source.Mass / r
+}
+
+func (Newtonian) String() string {
+	return "newton"
+}
+
+// Coulomb is the electrostatic force between charged bodies. Like charges
+// repel and opposite charges attract. Massless bodies are not accelerated.
+type Coulomb struct{}
+
+func (Coulomb) Acceleration(b, source *Body) vector.Vector {
+	if b.Mass == 0 {
+		return vector.Vector{0, 0, 0}
+	}
+	d, r := separation(b, source)
+	return vector.MultScalar(d, -Ke*b.Charge*source.Charge/(b.Mass*r*r*r))
+}
+
+func (Coulomb) Energy(b, source *Body) float64 {
+	_, r := separation(b, source)
+	return Ke * b.Charge * source.Charge / r
+}
+
+func (Coulomb) String() string {
+	return "coulomb"
+}
+
+// Yukawa is gravity screened with range Lambda: Phi = -G*m*exp(-r/Lambda)/r
+type Yukawa struct {
+	Lambda float64
+}
+
+func (y Yukawa) Acceleration(b, source *Body) vector.Vector {
+	d, r := separation(b, source)
+	x := r / y.Lambda
+	return vector.MultScalar(d, G*source.Mass*(1+x)*math.Exp(-x)/(r*r*r))
+}
+
+func (y Yukawa) Energy(b, source *Body) float64 {
+	_, r := separation(b, source)
+	return -G * b.Mass * source.Mass * math.Exp(-r/y.Lambda) / r
+}
+
+func (y Yukawa) String() string {
+	return fmt.Sprintf("yukawa lambda:%v", y.Lambda)
+}
+
+// MOND applies the "simple" interpolating function to each pair: the
+// Newtonian acceleration aN becomes aN/2 + sqrt(aN^2/4 + aN*A0). Applied
+// pairwise this is a toy model and does not conserve momentum for unequal
+// masses.
+type MOND struct {
+	A0 float64
+}
+
+func (m MOND) Acceleration(b, source *Body) vector.Vector {
+	d, r := separation(b, source)
+	an := G * source.Mass / (r * r)
+	a := an/2 + math.Sqrt(an*an/4+an*m.A0)
+	return vector.MultScalar(d, a/r)
+}
+
+// Potential of the simple interpolating function, which reduces to -G*m/r
+// close in and grows as sqrt(G*m*A0)*ln(r) far out.
+func (m MOND) Energy(b, source *Body) float64 {
+	_, r := separation(b, source)
+	u := G * source.Mass / (2 * r)
+	c := G * source.Mass * m.A0
+	s := math.Sqrt(u*u + c)
+	return b.Mass * (-u - s + math.Sqrt(c)*math.Log((math.Sqrt(c)+s)/u))
+}
+
+func (m MOND) String() string {
+	return fmt.Sprintf("mond a0:%v", m.A0)
+}
+
+// PowerLaw is attractive gravity falling off as 1/r^N. N = 2 is Newtonian.
+type PowerLaw struct {
+	N float64
+}
+
+func (p PowerLaw) Acceleration(b, source *Body) vector.Vector {
+	d, r := separation(b, source)
+	return vector.MultScalar(d, G*source.Mass/math.Pow(r, p.N+1))
+}
+
+func (p PowerLaw) Energy(b, source *Body) float64 {
+	_, r := separation(b, source)
+	if p.N == 1 {
+		return G * b.Mass * source.Mass * math.Log(r)
+	}
+	return -G * b.Mass * source.Mass / ((p.N - 1) * math.Pow(r, p.N-1))
+}
+
+func (p PowerLaw) String() string {
+	return fmt.Sprintf("power n:%v", p.N)
+}
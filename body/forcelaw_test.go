@@ -0,0 +1,66 @@
+package body
+
+import (
+	"github.com/seifertd/go/vector"
+	"math"
+	"testing"
+)
+
+// The acceleration of b should be minus the gradient of its energy divided by its mass
+func TestForceLawGradients(t *testing.T) {
+	laws := []ForceLaw{
+		Newtonian{},
+		Coulomb{},
+		Yukawa{2e9},
+		MOND{1.2e-10},
+		PowerLaw{3},
+		PowerLaw{1},
+	}
+	source := NewBody("source", 0, 0, 1, 2e30, 0, 0, nil)
+	source.Charge = 1e6
+	for _, law := range laws {
+		b := NewBody("b", 3e9, 4e9, 1, 5e24, 0, 0, nil)
+		b.Charge = 2e3
+		acc := law.Acceleration(b, source)
+		pos := b.Pos
+		h := pos.Magnitude() * 1e-6
+		grad := func(dx, dy float64) float64 {
+			b.Pos = vector.Add(pos, vector.New2DVector(dx, dy))
+			plus := law.Energy(b, source)
+			b.Pos = vector.Sub(pos, vector.New2DVector(dx, dy))
+			minus := law.Energy(b, source)
+			return -(plus - minus) / (2 * h * b.Mass)
+		}
+		numeric := vector.New2DVector(grad(h, 0), grad(0, h))
+		diff := vector.Sub(acc, numeric)
+		if diff.Magnitude() > 1e-5*acc.Magnitude() {
+			t.Errorf("%v: acceleration %v does not match -grad(energy)/m %v", law, acc, numeric)
+		}
+	}
+}
+
+func TestCoulombLikeChargesRepel(t *testing.T) {
+	b1 := NewBody("b1", 0, 0, 1, 1, 0, 0, nil)
+	b2 := NewBody("b2", 1, 0, 1, 1, 0, 0, nil)
+	b1.Charge = 1e-6
+	b2.Charge = 1e-6
+	if acc := (Coulomb{}).Acceleration(b1, b2); acc.X >= 0 {
+		t.Errorf("like charges should repel: %v", acc)
+	}
+	b2.Charge = -1e-6
+	if acc := (Coulomb{}).Acceleration(b1, b2); acc.X <= 0 {
+		t.Errorf("opposite charges should attract: %v", acc)
+	}
+}
+
+func TestLawsReduceToNewtonian(t *testing.T) {
+	source := NewBody("source", 0, 0, 1, 2e30, 0, 0, nil)
+	b := NewBody("b", 1e7, 0, 1, 1, 0, 0, nil)
+	newton := (Newtonian{}).Acceleration(b, source)
+	for _, law := range []ForceLaw{PowerLaw{2}, Yukawa{1e20}, MOND{1e-30}} {
+		acc := law.Acceleration(b, source)
+		if math.Abs(acc.X-newton.X) > 1e-6*math.Abs(newton.X) {
+			t.Errorf("%v should reduce to newtonian gravity: %v != %v", law, acc, newton)
+		}
+	}
+}
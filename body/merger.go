@@ -0,0 +1,73 @@
+package body
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Merger records one body absorbing another in a collision. The survivor
+// keeps its id, so following Into links gives the accretion history.
+type Merger struct {
+	Time           float64 `json:"time"`
+	Into           uint64  `json:"into"`
+	IntoName       string  `json:"into_name"`
+	IntoMass       float64 `json:"into_mass"`
+	Absorbed       uint64  `json:"absorbed"`
+	AbsorbedName   string  `json:"absorbed_name"`
+	AbsorbedMass   float64 `json:"absorbed_mass"`
+	ImpactVelocity float64 `json:"impact_velocity"`
+}
+
+// MergerTree is every merger of a run in the order they happened.
+type MergerTree []Merger
+
+// Record a merger of absorbed into into at time t, before CollideWith is
+// applied, so the masses are those of the two parents.
+func (mt *MergerTree) Record(t float64, into, absorbed *Body) {
+	*mt = append(*mt, Merger{
+		Time:           t,
+		Into:           into.Id,
+		IntoName:       into.Name,
+		IntoMass:       into.Mass,
+		Absorbed:       absorbed.Id,
+		AbsorbedName:   absorbed.Name,
+		AbsorbedMass:   absorbed.Mass,
+		ImpactVelocity: into.Vel.DistanceTo(absorbed.Vel),
+	})
+}
+
+func (mt MergerTree) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if mt == nil {
+		mt = MergerTree{}
+	}
+	return enc.Encode(mt)
+}
+
+// Write the tree as a Graphviz digraph with an edge from each absorbed body
+// to the body that absorbed it, labelled with the time and impact velocity.
+func (mt MergerTree) WriteDOT(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph mergers {"); err != nil {
+		return err
+	}
+	named := make(map[uint64]bool)
+	for _, m := range mt {
+		for _, node := range []struct {
+			id   uint64
+			name string
+		}{{m.Absorbed, m.AbsorbedName}, {m.Into, m.IntoName}} {
+			if !named[node.id] {
+				named[node.id] = true
+				fmt.Fprintf(w, "  %d [label=%q];\n", node.id, fmt.Sprintf("%v #%v", node.name, node.id))
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  %d -> %d [label=%q];\n", m.Absorbed, m.Into,
+			fmt.Sprintf("t=%v m=%.3g v=%.3g", m.Time, m.AbsorbedMass, m.ImpactVelocity)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -0,0 +1,47 @@
+package body
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMergerTree(t *testing.T) {
+	b1 := NewBody("b1", 0, 0, 10, 10, 0, 0, nil)
+	b2 := NewBody("b2", 0, 0, 5, 5, 3, 4, nil)
+	b3 := NewBody("b3", 0, 0, 5, 5, 0, 0, nil)
+	var tree MergerTree
+	tree.Record(10, b1, b2)
+	b1.CollideWith(b2)
+	tree.Record(20, b1, b3)
+	b1.CollideWith(b3)
+
+	if len(tree) != 2 {
+		t.Fatalf("tree should have 2 mergers: %v", len(tree))
+	}
+	if tree[0].Into != b1.Id || tree[0].Absorbed != b2.Id || tree[0].IntoMass != 10 || tree[0].ImpactVelocity != 5 {
+		t.Errorf("first merger not recorded correctly: %+v", tree[0])
+	}
+	if tree[1].IntoMass != 15 {
+		t.Errorf("second merger should record the grown mass: %+v", tree[1])
+	}
+
+	var buf bytes.Buffer
+	if err := tree.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded MergerTree
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[1] != tree[1] {
+		t.Errorf("JSON should round trip: %v %+v", err, decoded)
+	}
+
+	buf.Reset()
+	if err := tree.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	if !strings.HasPrefix(dot, "digraph") || strings.Count(dot, "->") != 2 {
+		t.Errorf("DOT output should have an edge per merger:\n%v", dot)
+	}
+}
@@ -0,0 +1,147 @@
+package body
+
+import (
+	"github.com/faiface/pixel"
+	"github.com/seifertd/go/vector"
+	"math"
+)
+
+// Elements are classical Keplerian orbital elements. A is the semi-major
+// axis in meters, negative for hyperbolic orbits (E > 1). Angles are in
+// radians: I inclination, Node longitude of the ascending node, Peri
+// argument of periapsis and M the mean anomaly at the epoch. Orbits with
+// I = 0 stay in the xy plane, where Node + Peri is the direction of
+// periapsis, and I = Pi gives a planar retrograde orbit.
+type Elements struct {
+	A    float64
+	E    float64
+	I    float64
+	Node float64
+	Peri float64
+	M    float64
+}
+
+// Eccentric (or hyperbolic) anomaly from the mean anomaly by Newton's method
+func (el Elements) anomaly() float64 {
+	if el.E < 1 {
+		m := math.Remainder(el.M, 2*math.Pi)
+		ea := m
+		if el.E > 0.8 {
+			ea = math.Pi * math.Copysign(1, m)
+		}
+		for i := 0; i < 100; i++ {
+			d := (ea - el.E*math.Sin(ea) - m) / (1 - el.E*math.Cos(ea))
+			ea -= d
+			if math.Abs(d) < 1e-14 {
+				break
+			}
+		}
+		return ea
+	}
+	f := math.Asinh(el.M / el.E)
+	for i := 0; i < 100; i++ {
+		d := (el.E*math.Sinh(f) - f - el.M) / (el.E*math.Cosh(f) - 1)
+		f -= d
+		if math.Abs(d) < 1e-14 {
+			break
+		}
+	}
+	return f
+}
+
+// Rotate a vector in the orbital plane, x toward periapsis, into the
+// reference frame
+func (el Elements) rotate(x, y float64) vector.Vector {
+	cn, sn := math.Cos(el.Node), math.Sin(el.Node)
+	cp, sp := math.Cos(el.Peri), math.Sin(el.Peri)
+	ci, si := math.Cos(el.I), math.Sin(el.I)
+	return vector.Vector{
+		(cn*cp-sn*sp*ci)*x + (-cn*sp-sn*cp*ci)*y,
+		(sn*cp+cn*sp*ci)*x + (-sn*sp+cn*cp*ci)*y,
+		(sp*si)*x + (cp*si)*y,
+	}
+}
+
+// StateVector gives the position and velocity relative to the central body
+// for the gravitational parameter mu = G*(M+m).
+func (el Elements) StateVector(mu float64) (vector.Vector, vector.Vector) {
+	var x, y, vx, vy float64
+	if el.E < 1 {
+		ea := el.anomaly()
+		b := el.A * math.Sqrt(1-el.E*el.E)
+		r := el.A * (1 - el.E*math.Cos(ea))
+		x = el.A * (math.Cos(ea) - el.E)
+		y = b * math.Sin(ea)
+		f := math.Sqrt(mu*el.A) / r
+		vx = -f * math.Sin(ea)
+		vy = f * math.Sqrt(1-el.E*el.E) * math.Cos(ea)
+	} else {
+		f := el.anomaly()
+		b := -el.A * math.Sqrt(el.E*el.E-1)
+		r := el.A * (1 - el.E*math.Cosh(f))
+		x = el.A * (math.Cosh(f) - el.E)
+		y = b * math.Sinh(f)
+		g := math.Sqrt(-mu*el.A) / r
+		vx = -g * math.Sinh(f)
+		vy = g * math.Sqrt(el.E*el.E-1) * math.Cosh(f)
+	}
+	return el.rotate(x, y), el.rotate(vx, vy)
+}
+
+// ElementsOf gives the elements of the orbit with position pos and velocity
+// vel relative to the central body, the inverse of StateVector. Node is 0
+// for orbits in the xy plane and Peri is 0 for circular orbits, where M is
+// then measured from the node.
+func ElementsOf(pos, vel vector.Vector, mu float64) Elements {
+	r := pos.Magnitude()
+	h := Cross(pos, vel)
+	ev := vector.DivScalar(vector.Sub(vector.MultScalar(pos, vel.Dot(vel)-mu/r), vector.MultScalar(vel, pos.Dot(vel))), mu)
+	el := Elements{A: 1 / (2/r - vel.Dot(vel)/mu), E: ev.Magnitude()}
+	el.I = math.Acos(math.Max(-1, math.Min(1, h.Z/h.Magnitude())))
+	if n := math.Hypot(h.X, h.Y); n > 1e-12*h.Magnitude() {
+		el.Node = math.Atan2(h.X, -h.Y)
+	}
+	// Unit vectors along the node and 90 degrees ahead in the orbital plane
+	p := vector.Vector{math.Cos(el.Node), math.Sin(el.Node), 0}
+	q := Cross(h.Unit(), p)
+	if el.E > 1e-12 {
+		el.Peri = math.Atan2(ev.Dot(q), ev.Dot(p))
+	}
+	nu := math.Atan2(pos.Dot(q), pos.Dot(p)) - el.Peri
+	if el.E < 1 {
+		ea := 2 * math.Atan(math.Sqrt((1-el.E)/(1+el.E))*math.Tan(nu/2))
+		el.M = ea - el.E*math.Sin(ea)
+	} else {
+		f := 2 * math.Atanh(math.Sqrt((el.E-1)/(el.E+1))*math.Tan(nu/2))
+		el.M = el.E*math.Sinh(f) - f
+	}
+	return el
+}
+
+// Orbital period in seconds, +Inf for unbound orbits
+func (el Elements) Period(mu float64) float64 {
+	if el.E >= 1 {
+		return math.Inf(1)
+	}
+	return 2 * math.Pi * math.Sqrt(el.A*el.A*el.A/mu)
+}
+
+// Radius of the Hill sphere of a body of mass m on this orbit around a body
+// of mass M, at periapsis
+func (el Elements) HillRadius(m, M float64) float64 {
+	return el.A * (1 - el.E) * math.Cbrt(m/(3*M))
+}
+
+// NewOrbitingBody creates a body on the orbit el around parent, relative
+// to the parent's position and velocity.
+func NewOrbitingBody(name string, parent *Body, el Elements, r float64, m float64, s *pixel.Sprite) *Body {
+	pos, vel := el.StateVector(G * (parent.Mass + m))
+	return NewBodyVector(name, vector.Add(parent.Pos, pos), vector.Add(parent.Vel, vel), r, m, s)
+}
+
+// NewOrbitingTestParticle creates a test particle on the orbit el around
+// parent.
+func NewOrbitingTestParticle(name string, parent *Body, el Elements, r float64, s *pixel.Sprite) *Body {
+	pos, vel := el.StateVector(G * parent.Mass)
+	return NewTestParticle(name, vector.Add(parent.Pos, pos), vector.Add(parent.Vel, vel), r, s)
+}
@@ -0,0 +1,98 @@
+package body
+
+import (
+	"github.com/seifertd/go/vector"
+	"math"
+	"testing"
+)
+
+func TestStateVector(t *testing.T) {
+	mu := G * 2e30
+	for _, el := range []Elements{
+		{A: 1.5e11, E: 0.0167, I: 0.1, Node: 1, Peri: 2, M: 3},
+		{A: 2e11, E: 0.9, I: 2.5, Node: -1, Peri: 0.5, M: 0.1},
+		{A: -1e11, E: 1.8, I: 0.7, Node: 2, Peri: 4, M: -2},
+	} {
+		pos, vel := el.StateVector(mu)
+		r := pos.Magnitude()
+		// vis-viva
+		if v2, want := vel.Dot(vel), mu*(2/r-1/el.A); math.Abs(v2-want) > 1e-9*want {
+			t.Errorf("%+v: v^2 %v, vis-viva gives %v", el, v2, want)
+		}
+		// angular momentum is along the orbit normal and fixed by a and e
+		h := Cross(pos, vel)
+		normal := vector.Vector{math.Sin(el.I) * math.Sin(el.Node), -math.Sin(el.I) * math.Cos(el.Node), math.Cos(el.I)}
+		if d := h.Unit().DistanceTo(normal); d > 1e-9 {
+			t.Errorf("%+v: orbit normal off by %v", el, d)
+		}
+		if want := math.Sqrt(mu * el.A * (1 - el.E*el.E)); math.Abs(h.Magnitude()-want) > 1e-9*want {
+			t.Errorf("%+v: angular momentum %v, expected %v", el, h.Magnitude(), want)
+		}
+	}
+
+	// At M = 0 the body is at periapsis
+	el := Elements{A: 1e11, E: 0.5, Peri: math.Pi / 2}
+	pos, _ := el.StateVector(mu)
+	if math.Abs(pos.X) > 1e-3 || math.Abs(pos.Y-5e10) > 1e-3 {
+		t.Errorf("should be at periapsis along y: %v", pos)
+	}
+	if p := el.Period(mu); math.Abs(p-2*math.Pi*math.Sqrt(1e33/mu)) > 1e-6 {
+		t.Errorf("wrong period %v", p)
+	}
+}
+
+func TestHillRadius(t *testing.T) {
+	earth := Elements{A: 1.496e11, E: 0.0167}
+	if r := earth.HillRadius(5.972e24, 1.989e30); math.Abs(r-1.472e9) > 1e6 {
+		t.Errorf("wrong Hill radius of the Earth %v", r)
+	}
+}
+
+func TestNewOrbitingBody(t *testing.T) {
+	sun := NewBody("sun", 1e11, 0, 7e8, 2e30, 0, 1e4, nil)
+	planet := NewOrbitingBody("planet", sun, Elements{A: 1.5e11, M: math.Pi / 2}, 6e6, 6e24, nil)
+	if d := planet.Pos.DistanceTo(vector.Vector{1e11, 1.5e11, 0}); d > 1e-3 {
+		t.Errorf("planet should be a quarter orbit around the sun: %v", planet.Pos)
+	}
+	v := math.Sqrt(G * (sun.Mass + planet.Mass) / 1.5e11)
+	if d := planet.Vel.DistanceTo(vector.Vector{-v, 1e4, 0}); d > 1e-9 {
+		t.Errorf("planet should be on a circular orbit moving with the sun: %v", planet.Vel)
+	}
+	retro := NewOrbitingTestParticle("retro", sun, Elements{A: 1.5e11, I: math.Pi}, 1, nil)
+	if h := Cross(vector.Sub(retro.Pos, sun.Pos), vector.Sub(retro.Vel, sun.Vel)); h.Z >= 0 || !retro.TestParticle {
+		t.Errorf("I = Pi should give a retrograde test particle: %v", h)
+	}
+}
+
+func TestElementsOf(t *testing.T) {
+	mu := G * 2e30
+	for _, el := range []Elements{
+		{A: 1.5e11, E: 0.0167, I: 0.1, Node: 1, Peri: 2, M: 3},
+		{A: 2e11, E: 0.9, I: 2.5, Node: -1, Peri: 0.5, M: 0.1},
+		{A: -1e11, E: 1.8, I: 0.7, Node: 2, Peri: -2, M: -2},
+		// Degenerate: planar, circular and planar retrograde
+		{A: 1e11, E: 0.3, Peri: 1, M: 1},
+		{A: 1e11, I: 0.2, Node: 1, M: 2},
+		{A: 1e11, E: 0.1, I: math.Pi, Peri: 1, M: -1},
+	} {
+		pos, vel := el.StateVector(mu)
+		got := ElementsOf(pos, vel, mu)
+		if math.Abs(got.A-el.A) > 1e-9*math.Abs(el.A) || math.Abs(got.E-el.E) > 1e-9 ||
+			math.Abs(got.I-el.I) > 1e-9 {
+			t.Errorf("%+v: got %+v", el, got)
+		}
+		pos2, vel2 := got.StateVector(mu)
+		if d := pos2.DistanceTo(pos); d > 1e-6*pos.Magnitude() {
+			t.Errorf("%+v: position off by %v with %+v", el, d, got)
+		}
+		if d := vel2.DistanceTo(vel); d > 1e-6*vel.Magnitude() {
+			t.Errorf("%+v: velocity off by %v with %+v", el, d, got)
+		}
+	}
+	el := Elements{A: 1.5e11, E: 0.2, I: 0.3, Node: 1, Peri: 2, M: 3}
+	pos, vel := el.StateVector(mu)
+	if got := ElementsOf(pos, vel, mu); math.Abs(got.Node-1) > 1e-9 || math.Abs(got.Peri-2) > 1e-9 ||
+		math.Abs(got.M-3) > 1e-9 {
+		t.Errorf("angles not recovered: %+v", got)
+	}
+}
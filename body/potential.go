@@ -0,0 +1,109 @@
+package body
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"math"
+)
+
+// Potential is a static background field that is not tied to any body. It
+// contributes an acceleration at any position and a potential energy per
+// unit mass, used for energy diagnostics and contour drawing.
+type Potential interface {
+	Acceleration(pos vector.Vector) vector.Vector
+	Energy(pos vector.Vector) float64
+	String() string
+}
+
+// UniformField is a constant acceleration everywhere, e.g. surface gravity.
+type UniformField struct {
+	Field vector.Vector
+}
+
+func (u UniformField) Acceleration(pos vector.Vector) vector.Vector {
+	return u.Field
+}
+
+func (u UniformField) Energy(pos vector.Vector) float64 {
+	return -u.Field.Dot(pos)
+}
+
+func (u UniformField) String() string {
+	return fmt.Sprintf("uniform g:%v,%v,%v", u.Field.X, u.Field.Y, u.Field.Z)
+}
+
+// PointPotential is the field of a fixed point mass at Center.
+type PointPotential struct {
+	Center vector.Vector
+	Mass   float64
+}
+
+func (p PointPotential) Acceleration(pos vector.Vector) vector.Vector {
+	d := vector.Sub(p.Center, pos)
+	r := d.Magnitude()
+	if r == 0 {
+		return vector.Vector{0, 0, 0}
+	}
+	return vector.MultScalar(d, G*p.Mass/(r*r*r))
+}
+
+func (p PointPotential) Energy(pos vector.Vector) float64 {
+	return -G * p.Mass / pos.DistanceTo(p.Center)
+}
+
+func (p PointPotential) String() string {
+	return fmt.Sprintf("point m:%v at %v,%v", p.Mass, p.Center.X, p.Center.Y)
+}
+
+// NFWHalo is a Navarro-Frenk-White dark matter halo. Mass is the
+// characteristic mass 4*pi*rho0*rs^3 and ScaleRadius is rs.
+type NFWHalo struct {
+	Center      vector.Vector
+	Mass        float64
+	ScaleRadius float64
+}
+
+func (n NFWHalo) Acceleration(pos vector.Vector) vector.Vector {
+	d := vector.Sub(n.Center, pos)
+	r := d.Magnitude()
+	if r == 0 {
+		return vector.Vector{0, 0, 0}
+	}
+	x := r / n.ScaleRadius
+	enclosed := n.Mass * (math.Log1p(x) - x/(1+x))
+	return vector.MultScalar(d, G*enclosed/(r*r*r))
+}
+
+func (n NFWHalo) Energy(pos vector.Vector) float64 {
+	r := pos.DistanceTo(n.Center)
+	if r == 0 {
+		return -G * n.Mass / n.ScaleRadius
+	}
+	return -G * n.Mass * math.Log1p(r/n.ScaleRadius) / r
+}
+
+func (n NFWHalo) String() string {
+	return fmt.Sprintf("nfw m:%v rs:%v at %v,%v", n.Mass, n.ScaleRadius, n.Center.X, n.Center.Y)
+}
+
+// LogarithmicHalo has a flat rotation curve of speed V0 outside CoreRadius.
+type LogarithmicHalo struct {
+	Center     vector.Vector
+	V0         float64
+	CoreRadius float64
+}
+
+func (l LogarithmicHalo) Acceleration(pos vector.Vector) vector.Vector {
+	d := vector.Sub(l.Center, pos)
+	r2 := d.Dot(d)
+	return vector.MultScalar(d, l.V0*l.V0/(l.CoreRadius*l.CoreRadius+r2))
+}
+
+func (l LogarithmicHalo) Energy(pos vector.Vector) float64 {
+	d := vector.Sub(pos, l.Center)
+	return 0.5 * l.V0 * l.V0 * math.Log(l.CoreRadius*l.CoreRadius+d.Dot(d))
+}
+
+func (l LogarithmicHalo) String() string {
+	return fmt.Sprintf("log v0:%v rc:%v at %v,%v", l.V0, l.CoreRadius, l.Center.X, l.Center.Y)
+}
@@ -0,0 +1,46 @@
+package body
+
+import (
+	"github.com/seifertd/go/vector"
+	"math"
+	"testing"
+)
+
+// The acceleration of every potential should be minus the gradient of its energy
+func TestPotentialGradients(t *testing.T) {
+	tests := []struct {
+		p   Potential
+		pos vector.Vector
+	}{
+		{UniformField{vector.Vector{0, -9.8, 0}}, vector.Vector{3e3, 4e3, 0}},
+		{PointPotential{vector.Vector{1e9, 0, 0}, 5e24}, vector.Vector{3e9, 4e9, 0}},
+		{NFWHalo{vector.Vector{0, 0, 0}, 1e40, 3e19}, vector.Vector{-2e19, 5e19, 1e19}},
+		{NFWHalo{vector.Vector{0, 0, 0}, 1e40, 3e19}, vector.Vector{1e18, 0, 2e17}},
+		{LogarithmicHalo{vector.Vector{0, 0, 0}, 2e5, 1e19}, vector.Vector{-2e19, 5e19, 1e19}},
+		{LogarithmicHalo{vector.Vector{0, 0, 0}, 2e5, 1e19}, vector.Vector{4e18, 0, 0}},
+	}
+	for _, tt := range tests {
+		p, pos := tt.p, tt.pos
+		acc := p.Acceleration(pos)
+		h := pos.Magnitude() * 1e-6
+		grad := func(dx, dy, dz float64) float64 {
+			plus := vector.Add(pos, vector.Vector{dx, dy, dz})
+			minus := vector.Sub(pos, vector.Vector{dx, dy, dz})
+			return -(p.Energy(plus) - p.Energy(minus)) / (2 * h)
+		}
+		numeric := vector.Vector{grad(h, 0, 0), grad(0, h, 0), grad(0, 0, h)}
+		diff := vector.Sub(acc, numeric)
+		if diff.Magnitude() > 1e-5*acc.Magnitude() {
+			t.Errorf("%v: acceleration %v does not match -grad(energy) %v at %v", p, acc, numeric, pos)
+		}
+	}
+}
+
+func TestPointPotentialMatchesBody(t *testing.T) {
+	p := PointPotential{vector.Vector{0, 0, 0}, 5e24}
+	acc := p.Acceleration(vector.Vector{1e7, 0, 0})
+	expected := -G * 5e24 / 1e14
+	if math.Abs(acc.X-expected) > 1e-12*math.Abs(expected) || acc.Y != 0 {
+		t.Errorf("point potential acceleration %v != %v", acc, expected)
+	}
+}
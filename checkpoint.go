@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faiface/pixel"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"github.com/seifertd/nbody-go/horizons"
+	"os"
+	"sort"
+)
+
+// Version of the checkpoint format, checkpoints of other versions are
+// refused
+const checkpointVersion = 1
+
+// What the window shows, saved with checkpoints
+type camera struct {
+	// index of the body kept in the middle of the screen, -1 for none
+	Follow   int           `json:"follow"`
+	Offset   vector.Vector `json:"offset"`
+	Contours bool          `json:"contours"`
+}
+
+// A snapshot of everything a run depends on. Bodies refer to each other by
+// id; those gone from the world, like a flyby star that escaped, are saved
+// with the state they had when they left.
+type checkpoint struct {
+	Version int     `json:"version"`
+	Seed    int64   `json:"seed"`
+	Draws   uint64  `json:"rng_draws"`
+	Elapsed float64 `json:"elapsed"`
+	Dt      float64 `json:"dt"`
+	Spt     int     `json:"steps_per_tick"`
+	Running bool    `json:"running"`
+	Epoch   float64 `json:"epoch,omitempty"`
+
+	Width            int     `json:"width"`
+	Height           int     `json:"height"`
+	Scale            float64 `json:"scale"`
+	MetersPerPixel   float64 `json:"meters_per_pixel"`
+	Mag              float64 `json:"mag"`
+	FollowBarycenter bool    `json:"follow_barycenter,omitempty"`
+	Corotate         bool    `json:"corotate,omitempty"`
+	Camera           camera  `json:"camera"`
+
+	Law        string              `json:"law"`
+	Potentials []string            `json:"potentials,omitempty"`
+	Drag       *checkpointDrag     `json:"drag,omitempty"`
+	Bodies     []checkpointBody    `json:"bodies"`
+	Mergers    body.MergerTree     `json:"mergers,omitempty"`
+	Histogram  *checkpointHist     `json:"histogram,omitempty"`
+	Flyby      *checkpointFlyby    `json:"flyby,omitempty"`
+	Horizons   []*horizons.Vectors `json:"horizons,omitempty"`
+}
+
+type checkpointBody struct {
+	Id           uint64        `json:"id"`
+	Name         string        `json:"name"`
+	Pos          vector.Vector `json:"pos"`
+	Vel          vector.Vector `json:"vel"`
+	Acc          vector.Vector `json:"acc"`
+	Radius       float64       `json:"radius"`
+	Mass         float64       `json:"mass"`
+	TestParticle bool          `json:"test_particle,omitempty"`
+	Charge       float64       `json:"charge,omitempty"`
+	Spin         vector.Vector `json:"spin"`
+	Sprite       string        `json:"sprite,omitempty"`
+}
+
+type checkpointDrag struct {
+	Star uint64  `json:"star"`
+	Eta  float64 `json:"eta"`
+	Tau  float64 `json:"tau"`
+}
+
+type checkpointHist struct {
+	Center    checkpointBody  `json:"center"`
+	Perturber *checkpointBody `json:"perturber,omitempty"`
+	Lo        float64         `json:"lo"`
+	Hi        float64         `json:"hi"`
+	Counts    []int           `json:"counts"`
+	Every     float64         `json:"every"`
+	Next      float64         `json:"next"`
+	Samples   int             `json:"samples"`
+}
+
+type checkpointOrbit struct {
+	Id   uint64         `json:"id"`
+	Name string         `json:"name"`
+	Host checkpointBody `json:"host"`
+	El   body.Elements  `json:"elements"`
+}
+
+type checkpointFlyby struct {
+	Star     checkpointBody    `json:"star"`
+	Primary  checkpointBody    `json:"primary"`
+	Start    float64           `json:"start"`
+	Before   []checkpointOrbit `json:"before"`
+	Escaped  []uint64          `json:"escaped,omitempty"`
+	Reported bool              `json:"reported,omitempty"`
+}
+
+// Name of a loaded sprite, empty when it is not one
+func spriteName(s *pixel.Sprite) string {
+	for name, sprite := range sprites {
+		if sprite == s {
+			return name
+		}
+	}
+	return ""
+}
+
+func saveBody(b *body.Body) checkpointBody {
+	return checkpointBody{b.Id, b.Name, b.Pos, b.Vel, b.Acc, b.Radius, b.Mass, b.TestParticle, b.Charge, b.Spin,
+		spriteName(b.Sprite)}
+}
+
+// Snapshot of the world and what the window shows
+func (w World) checkpoint(cam camera) (checkpoint, error) {
+	c := checkpoint{
+		Version: checkpointVersion, Seed: w.seed, Elapsed: w.elapsed, Dt: w.dt, Spt: w.spt, Running: w.running,
+		Epoch: w.epoch, Width: w.width, Height: w.height, Scale: w.scale, MetersPerPixel: w.mpp, Mag: w.mag,
+		FollowBarycenter: w.followBarycenter, Corotate: w.corotate, Camera: cam, Mergers: w.mergers,
+		Horizons: w.horizons,
+	}
+	if w.source != nil {
+		c.Seed, c.Draws = w.source.seed, w.source.draws
+	}
+	var err error
+	if c.Law, err = forceLawSpec(w.forceLaw()); err != nil {
+		return c, err
+	}
+	for _, p := range w.potentials {
+		spec, err := potentialSpec(p)
+		if err != nil {
+			return c, err
+		}
+		c.Potentials = append(c.Potentials, spec)
+	}
+	if w.drag != nil {
+		c.Drag = &checkpointDrag{w.drag.Star.Id, w.drag.Eta, w.drag.Tau}
+	}
+	for _, b := range w.bodies {
+		c.Bodies = append(c.Bodies, saveBody(b))
+	}
+	if hg := w.histogram; hg != nil {
+		c.Histogram = &checkpointHist{Center: saveBody(hg.center), Lo: hg.lo, Hi: hg.hi, Counts: hg.counts,
+			Every: hg.every, Next: hg.next, Samples: hg.samples}
+		if hg.perturber != nil {
+			perturber := saveBody(hg.perturber)
+			c.Histogram.Perturber = &perturber
+		}
+	}
+	if f := w.flyby; f != nil {
+		c.Flyby = &checkpointFlyby{Star: saveBody(f.star), Primary: saveBody(f.primary), Start: f.start,
+			Reported: f.reported}
+		for id, orbit := range f.before {
+			c.Flyby.Before = append(c.Flyby.Before, checkpointOrbit{id, orbit.name, saveBody(orbit.host), orbit.el})
+		}
+		for id := range f.escaped {
+			c.Flyby.Escaped = append(c.Flyby.Escaped, id)
+		}
+		sort.Slice(c.Flyby.Before, func(i, j int) bool { return c.Flyby.Before[i].Id < c.Flyby.Before[j].Id })
+		sort.Slice(c.Flyby.Escaped, func(i, j int) bool { return c.Flyby.Escaped[i] < c.Flyby.Escaped[j] })
+	}
+	return c, nil
+}
+
+// Write a checkpoint of the world to path
+func (w World) saveCheckpoint(path string, cam camera) error {
+	c, err := w.checkpoint(cam)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// Write next to the old checkpoint first so a crash never leaves a
+	// half written one
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Rebuild a body, keeping its id
+func (c checkpointBody) restore() *body.Body {
+	b := body.NewBodyVector(c.Name, c.Pos, c.Vel, c.Radius, c.Mass, nil)
+	b.Id, b.Acc, b.TestParticle, b.Charge, b.Spin = c.Id, c.Acc, c.TestParticle, c.Charge, c.Spin
+	body.ReserveId(c.Id)
+	b.Sprite = sprites[c.Sprite]
+	if b.Sprite == nil {
+		b.Sprite = sprites["circle"]
+	}
+	return b
+}
+
+// The world and camera of a checkpoint, continuing exactly where the saved
+// run was
+func (c checkpoint) restore() (*World, camera, error) {
+	if c.Version != checkpointVersion {
+		return nil, c.Camera, fmt.Errorf("checkpoint version %v, expected %v", c.Version, checkpointVersion)
+	}
+	w := &World{
+		scale: c.Scale, mpp: c.MetersPerPixel, spt: c.Spt, running: c.Running, elapsed: c.Elapsed,
+		width: c.Width, height: c.Height, mag: c.Mag, seed: c.Seed, mergers: c.Mergers, dt: c.Dt,
+		followBarycenter: c.FollowBarycenter, epoch: c.Epoch, corotate: c.Corotate, horizons: c.Horizons,
+	}
+	w.source = newSource(c.Seed)
+	w.source.skipTo(c.Draws)
+	law, _, err := parseForceLaw(c.Law)
+	if err != nil {
+		return nil, c.Camera, fmt.Errorf("law: %v", err)
+	}
+	if _, newton := law.(body.Newtonian); !newton {
+		w.law = law
+	}
+	for _, spec := range c.Potentials {
+		p, err := parsePotentials(spec)
+		if err != nil {
+			return nil, c.Camera, fmt.Errorf("potentials: %v", err)
+		}
+		w.potentials = append(w.potentials, p...)
+	}
+
+	byId := map[uint64]*body.Body{}
+	for _, cb := range c.Bodies {
+		b := cb.restore()
+		w.bodies = append(w.bodies, b)
+		byId[b.Id] = b
+	}
+	// Bodies still in the world, or rebuilt as they were when they left
+	find := func(cb checkpointBody) *body.Body {
+		if b, ok := byId[cb.Id]; ok {
+			return b
+		}
+		return cb.restore()
+	}
+	if c.Drag != nil {
+		star, ok := byId[c.Drag.Star]
+		if !ok {
+			return nil, c.Camera, fmt.Errorf("drag: no star %v", c.Drag.Star)
+		}
+		w.drag = &body.GasDrag{Star: star, Eta: c.Drag.Eta, Tau: c.Drag.Tau}
+	}
+	if hg := c.Histogram; hg != nil {
+		w.histogram = &smaHistogram{center: find(hg.Center), lo: hg.Lo, hi: hg.Hi, counts: hg.Counts,
+			every: hg.Every, next: hg.Next, samples: hg.Samples}
+		if hg.Perturber != nil {
+			w.histogram.perturber = find(*hg.Perturber)
+		}
+	}
+	if f := c.Flyby; f != nil {
+		w.flyby = &flyby{star: find(f.Star), primary: find(f.Primary), start: f.Start, reported: f.Reported,
+			before: map[uint64]flybyOrbit{}, escaped: map[uint64]bool{}}
+		for _, o := range f.Before {
+			w.flyby.before[o.Id] = flybyOrbit{o.Name, find(o.Host), o.El}
+		}
+		for _, id := range f.Escaped {
+			w.flyby.escaped[id] = true
+		}
+	}
+	return w, c.Camera, nil
+}
+
+// Load the world and camera of the checkpoint at path
+func loadCheckpoint(path string) (*World, camera, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, camera{Follow: -1}, err
+	}
+	var c checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, camera{Follow: -1}, fmt.Errorf("%v: %v", path, err)
+	}
+	w, cam, err := c.restore()
+	if err != nil {
+		return nil, cam, fmt.Errorf("%v: %v", path, err)
+	}
+	return w, cam, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	math_rand "math/rand"
+	"os"
+	"testing"
+)
+
+// A run resumed from a checkpoint continues bit for bit like the run that
+// was never interrupted
+func TestCheckpointResume(t *testing.T) {
+	resetSprites()
+	source := newSource(99)
+	rng := math_rand.New(source)
+	world := randomWorld(1024, 1024, 30, 0.5, 0.3, orbitSpread{0.2, 0.1}, rng)
+	addTestParticles(world, 20, 0.3, orbitSpread{}, rng)
+	params, _ := parseFlyby("star,m=1e28,b=1e9,v=2e4,d=5e9")
+	world.flyby, _ = addFlyby(world, params)
+	world.toBarycentricFrame()
+	world.seed, world.source = 99, source
+	world.law = body.PowerLaw{N: 2.01}
+	world.potentials, _ = parsePotentials("uniform,gx=1e-6;point,m=1e20,x=1e12")
+	world.drag = &body.GasDrag{Star: world.bodies[0], Eta: 0.002, Tau: 1e3}
+	world.histogram = newSMAHistogram(world, 1e8, 1e9, 20, 10*world.timestep())
+	world.spt = 20
+	for i := 0; i < 10; i++ {
+		world.tick()
+	}
+
+	path := t.TempDir() + "/checkpoint.json"
+	cam := camera{Follow: 3, Offset: vector.Vector{10, 20, 0}, Contours: true}
+	if err := world.saveCheckpoint(path, cam); err != nil {
+		t.Fatal(err)
+	}
+	resumed, resumedCam, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumedCam != cam {
+		t.Errorf("camera %v, expected %v", resumedCam, cam)
+	}
+	if rng.Int63() != math_rand.New(resumed.source).Int63() {
+		t.Errorf("random source not restored")
+	}
+	for i := 0; i < 10; i++ {
+		world.tick()
+		resumed.tick()
+	}
+	if len(world.bodies) != len(resumed.bodies) || world.elapsed != resumed.elapsed {
+		t.Fatalf("resumed run has %v bodies at %v, expected %v at %v", len(resumed.bodies), resumed.elapsed,
+			len(world.bodies), world.elapsed)
+	}
+	for i, b := range world.bodies {
+		r := resumed.bodies[i]
+		if b.Id != r.Id || b.Pos != r.Pos || b.Vel != r.Vel || b.Mass != r.Mass || b.Spin != r.Spin {
+			t.Fatalf("body %v diverged after resuming: %v vs %v", i, b, r)
+		}
+	}
+	if len(world.mergers) != len(resumed.mergers) || world.histogram.samples != resumed.histogram.samples ||
+		fmt.Sprint(world.histogram.counts) != fmt.Sprint(resumed.histogram.counts) {
+		t.Errorf("mergers or histogram diverged after resuming")
+	}
+	if fmt.Sprint(world.flyby.outcomes(world)) != fmt.Sprint(resumed.flyby.outcomes(resumed)) {
+		t.Errorf("flyby outcomes diverged: %v vs %v", world.flyby.outcomes(world), resumed.flyby.outcomes(resumed))
+	}
+	if b := body.NewBody("new", 0, 0, 1, 1, 0, 0, nil); b.Id <= world.bodies[len(world.bodies)-1].Id {
+		t.Errorf("new bodies must not reuse restored ids")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadCheckpoint(path); err == nil {
+		t.Errorf("a checkpoint of another version should be refused")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	math_rand "math/rand"
+	"sort"
+)
+
+const (
+	// Radius given to cluster stars, one solar radius
+	starRadius = 6.957e8
+	// Plummer spheres are cut off at this many scale radii
+	plummerCutoff = 20.0
+)
+
+// Shape and scale of a generated star cluster
+type clusterSpec struct {
+	n      int
+	mass   float64
+	radius float64
+	// virial ratio T/|W| to start from, 0.5 is equilibrium
+	virial float64
+	// flatten the cluster onto the xy plane
+	projected bool
+}
+
+// Point uniformly distributed on the unit sphere
+func randomDirection(rng *math_rand.Rand) vector.Vector {
+	z := 2*rng.Float64() - 1
+	phi := rng.Float64() * math.Pi * 2
+	s := math.Sqrt(1 - z*z)
+	return vector.Vector{s * math.Cos(phi), s * math.Sin(phi), z}
+}
+
+// Plummer sphere by the method of Aarseth, Henon and Wielen (1974), in
+// units where G = M = a = 1
+func plummerSample(n int, rng *math_rand.Rand) ([]vector.Vector, []vector.Vector) {
+	pos := make([]vector.Vector, n)
+	vel := make([]vector.Vector, n)
+	for i := 0; i < n; i++ {
+		r := math.Inf(1)
+		for r > plummerCutoff {
+			r = 1 / math.Sqrt(math.Pow(rng.Float64(), -2.0/3.0)-1)
+		}
+		// Velocity as a fraction q of the escape velocity, with
+		// distribution q^2 (1 - q^2)^(7/2)
+		q, g := 0.0, 1.0
+		for 0.1*g > q*q*math.Pow(1-q*q, 3.5) {
+			q, g = rng.Float64(), rng.Float64()
+		}
+		v := q * math.Sqrt2 * math.Pow(1+r*r, -0.25)
+		pos[i] = vector.MultScalar(randomDirection(rng), r)
+		vel[i] = vector.MultScalar(randomDirection(rng), v)
+	}
+	return pos, vel
+}
+
+// Density of a King model relative to its value for W = 0 upward, in units
+// of the central velocity dispersion
+func kingDensity(w float64) float64 {
+	if w <= 0 {
+		return 0
+	}
+	return math.Exp(w)*math.Erf(math.Sqrt(w)) - math.Sqrt(4*w/math.Pi)*(1+2*w/3)
+}
+
+// Solution of Poisson's equation for a King model with central potential
+// w0, with r in core radii. Returns the radii, the potential and the
+// enclosed mass in arbitrary units out to the tidal radius.
+func kingProfile(w0 float64) ([]float64, []float64, []float64) {
+	rho0 := kingDensity(w0)
+	// d2W/dr2 + 2/r dW/dr = -9 rho/rho0, started from the series about
+	// the center
+	r := 1e-4
+	w, dw := w0-1.5*r*r, -3*r
+	deriv := func(r, w, dw float64) (float64, float64) {
+		return dw, -9*kingDensity(w)/rho0 - 2*dw/r
+	}
+	radii := []float64{0}
+	potential := []float64{w0}
+	mass := []float64{0}
+	for w > 0 {
+		h := 1e-3 * math.Max(1, r)
+		k1w, k1d := deriv(r, w, dw)
+		k2w, k2d := deriv(r+h/2, w+h/2*k1w, dw+h/2*k1d)
+		k3w, k3d := deriv(r+h/2, w+h/2*k2w, dw+h/2*k2d)
+		k4w, k4d := deriv(r+h, w+h*k3w, dw+h*k3d)
+		w += h / 6 * (k1w + 2*k2w + 2*k3w + k4w)
+		dw += h / 6 * (k1d + 2*k2d + 2*k3d + k4d)
+		r += h
+		radii = append(radii, r)
+		potential = append(potential, math.Max(w, 0))
+		mass = append(mass, -r*r*dw)
+	}
+	return radii, potential, mass
+}
+
+// King model with central potential w0, r in core radii and v in units of
+// the velocity dispersion
+func kingSample(n int, w0 float64, rng *math_rand.Rand) ([]vector.Vector, []vector.Vector) {
+	radii, potential, mass := kingProfile(w0)
+	total := mass[len(mass)-1]
+	pos := make([]vector.Vector, n)
+	vel := make([]vector.Vector, n)
+	for i := 0; i < n; i++ {
+		m := rng.Float64() * total
+		j := sort.SearchFloat64s(mass, m)
+		if j == 0 {
+			j = 1
+		}
+		f := (m - mass[j-1]) / (mass[j] - mass[j-1])
+		r := radii[j-1] + f*(radii[j]-radii[j-1])
+		w := potential[j-1] + f*(potential[j]-potential[j-1])
+
+		// Speeds up to escape with distribution v^2 (exp(W - v^2/2) - 1)
+		vmax := math.Sqrt(2 * w)
+		dist := func(v float64) float64 { return v * v * (math.Exp(w-v*v/2) - 1) }
+		peak := 0.0
+		for k := 1; k <= 100; k++ {
+			peak = math.Max(peak, dist(vmax*float64(k)/100))
+		}
+		v := 0.0
+		for vmax > 0 {
+			v = rng.Float64() * vmax
+			if rng.Float64()*peak*1.1 <= dist(v) {
+				break
+			}
+		}
+		pos[i] = vector.MultScalar(randomDirection(rng), r)
+		vel[i] = vector.MultScalar(randomDirection(rng), v)
+	}
+	return pos, vel
+}
+
+// Build a cluster world from positions and velocities in model units.
+// Positions are scaled to the cluster radius, and velocities so the
+// cluster starts at the requested virial ratio.
+func clusterWorld(w, h int, spec clusterSpec, pos, vel []vector.Vector) *World {
+	world := &World{
+		scale:   1.0,
+		spt:     10,
+		running: true,
+		elapsed: 0,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+	}
+	sprite := sprites["sun"]
+	if circleMode {
+		sprite = sprites["circle"]
+	}
+	for i := range pos {
+		p := vector.MultScalar(pos[i], spec.radius)
+		v := vel[i]
+		if spec.projected {
+			p.Z, v.Z = 0, 0
+		}
+		world.bodies = append(world.bodies, body.NewBodyVector(fmt.Sprintf("S%v", i), p, v,
+			starRadius, spec.mass/float64(spec.n), sprite))
+	}
+	world.toBarycentricFrame()
+	if t := world.kineticEnergy(); t > 0 {
+		f := math.Sqrt(spec.virial * math.Abs(world.potentialEnergy()) / t)
+		for _, b := range world.bodies {
+			b.Vel.MultScalar(f)
+		}
+	}
+
+	// Dynamical time of the cluster, sqrt(R^3/GM)
+	world.dt = math.Sqrt(math.Pow(spec.radius, 3)/(G*spec.mass)) / 1000
+	rh := world.halfMassRadius()
+	world.mpp = 10 * rh * world.scale / float64(min(w, h))
+	fmt.Printf("Cluster of %v stars, %.4g kg\n", spec.n, spec.mass)
+	fmt.Printf("Virial ratio T/|W| %.4f, half-mass radius %.4g m\n", world.virialRatio(), rh)
+	return world
+}
+
+func plummerCluster(w, h int, spec clusterSpec, rng *math_rand.Rand) *World {
+	pos, vel := plummerSample(spec.n, rng)
+	return clusterWorld(w, h, spec, pos, vel)
+}
+
+// King model cluster, the radius of the spec is the core radius
+func kingCluster(w, h int, spec clusterSpec, w0 float64, rng *math_rand.Rand) *World {
+	pos, vel := kingSample(spec.n, w0, rng)
+	return clusterWorld(w, h, spec, pos, vel)
+}
+
+// Ratio of kinetic energy, in the barycentric frame, to the magnitude of
+// the potential energy
+func (w World) virialRatio() float64 {
+	pot := w.potentialEnergy()
+	if pot == 0 {
+		return math.Inf(1)
+	}
+	_, vel := w.barycenter()
+	t := 0.0
+	for _, b := range w.bodies {
+		v := vector.Sub(b.Vel, vel)
+		t += 0.5 * b.Mass * v.Dot(v)
+	}
+	return t / math.Abs(pot)
+}
+
+// Radius around the barycenter holding half of the mass
+func (w World) halfMassRadius() float64 {
+	center, _ := w.barycenter()
+	type shell struct{ r, m float64 }
+	shells := make([]shell, 0, len(w.bodies))
+	total := 0.0
+	for _, b := range w.bodies {
+		shells = append(shells, shell{b.Pos.DistanceTo(center), b.Mass})
+		total += b.Mass
+	}
+	sort.Slice(shells, func(i, j int) bool { return shells[i].r < shells[j].r })
+	m := 0.0
+	for _, s := range shells {
+		m += s.m
+		if m >= total/2 {
+			return s.r
+		}
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	resetSprites()
+	spec := clusterSpec{n: 2000, mass: 2e34, radius: 3e16, virial: 0.5}
+	world := plummerCluster(1024, 1024, spec, newRand(1))
+	if q := world.virialRatio(); math.Abs(q-0.5) > 1e-9 {
+		t.Errorf("plummer sphere should start in virial equilibrium: %v", q)
+	}
+	// The half-mass radius of a Plummer sphere is 1.305 scale radii
+	if rh := world.halfMassRadius() / spec.radius; math.Abs(rh-1.305) > 0.1 {
+		t.Errorf("plummer half-mass radius %v scale radii", rh)
+	}
+
+	// King (1966): log10 of the tidal over core radius is 1.26 for W0 = 6
+	radii, _, _ := kingProfile(6)
+	if c := math.Log10(radii[len(radii)-1]); math.Abs(c-1.26) > 0.02 {
+		t.Errorf("king concentration for W0 = 6 is %v", c)
+	}
+	spec.virial, spec.projected = 0.3, true
+	world = kingCluster(1024, 1024, spec, 6, newRand(1))
+	for _, b := range world.bodies {
+		if b.Pos.Z != 0 || b.Vel.Z != 0 {
+			t.Fatalf("projected cluster should be flat: %v", b)
+		}
+	}
+	if q := world.virialRatio(); math.Abs(q-0.3) > 1e-9 {
+		t.Errorf("virial ratio should be as asked: %v", q)
+	}
+}
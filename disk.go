@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	math_rand "math/rand"
+)
+
+const (
+	// Bulk density of planetesimals, rocky
+	planetesimalDensity = 3000.0
+	// Rayleigh distributed eccentricities are capped to keep orbits bound
+	maxDiskEccentricity = 0.9
+)
+
+// Draws a planetesimal mass in kg
+type massSpectrum func(rng *math_rand.Rand) float64
+
+// Parse a mass spectrum spec: "power,q=<q>,min=<kg>,max=<kg>" for dN/dm
+// proportional to m^-q between min and max, or "lognormal,m=<kg>,sigma=<s>"
+// for masses with median m and standard deviation sigma in ln(m).
+func parseMassSpectrum(spec string) (massSpectrum, error) {
+	kind, params, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	allowed := map[string][]string{
+		"power":     {"q", "min", "max"},
+		"lognormal": {"m", "sigma"},
+	}
+	if err := checkSpec("mass spectrum", kind, params, allowed); err != nil {
+		return nil, err
+	}
+	if kind == "lognormal" {
+		m, sigma := params["m"], params["sigma"]
+		if m <= 0 || sigma < 0 {
+			return nil, fmt.Errorf("mass spectrum lognormal requires m > 0 and sigma >= 0")
+		}
+		return func(rng *math_rand.Rand) float64 {
+			return m * math.Exp(sigma*rng.NormFloat64())
+		}, nil
+	}
+	q, lo, hi := params["q"], params["min"], params["max"]
+	if lo <= 0 || hi < lo {
+		return nil, fmt.Errorf("mass spectrum power requires 0 < min <= max")
+	}
+	return func(rng *math_rand.Rand) float64 {
+		return powerLawSample(lo, hi, 1-q, rng)
+	}, nil
+}
+
+// Sample x in [lo, hi] with density proportional to x^(k-1)
+func powerLawSample(lo, hi, k float64, rng *math_rand.Rand) float64 {
+	u := rng.Float64()
+	if k == 0 {
+		return lo * math.Pow(hi/lo, u)
+	}
+	return math.Pow(math.Pow(lo, k)+u*(math.Pow(hi, k)-math.Pow(lo, k)), 1/k)
+}
+
+// Rayleigh distributed value with scale sigma
+func rayleigh(sigma float64, rng *math_rand.Rand) float64 {
+	return sigma * math.Sqrt(-2*math.Log(1-rng.Float64()))
+}
+
+// Shape of a protoplanetary disk
+type diskSpec struct {
+	n int
+	// inner and outer edge in meters
+	inner float64
+	outer float64
+	// surface density falls as r^-surface
+	surface float64
+	masses  massSpectrum
+	// Rayleigh scale of the eccentricities, inclinations get half of it
+	rayleighE float64
+	// planetesimal radii are multiplied by this to speed up collisions
+	inflate float64
+}
+
+// A star with n planetesimals drawn from the disk spec
+func protoplanetaryDisk(w, h int, spec diskSpec, rng *math_rand.Rand) *World {
+	world := &World{
+		scale:   1.0,
+		spt:     10,
+		running: true,
+		elapsed: 0,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+	}
+	sun := body.NewBody("Sol", 0, 0, solarBodies[0].radius, solarBodies[0].mass, 0, 0, sprites["sun"])
+	world.bodies = []*body.Body{sun}
+	total := 0.0
+	lightest, heaviest := math.Inf(1), 0.0
+	for i := 0; i < spec.n; i++ {
+		// Bodies per annulus follow the surface density times r
+		a := powerLawSample(spec.inner, spec.outer, 2-spec.surface, rng)
+		el := body.Elements{
+			A:    a,
+			E:    math.Min(rayleigh(spec.rayleighE, rng), maxDiskEccentricity),
+			I:    rayleigh(spec.rayleighE/2, rng),
+			Node: rng.Float64() * math.Pi * 2,
+			Peri: rng.Float64() * math.Pi * 2,
+			M:    rng.Float64() * math.Pi * 2,
+		}
+		m := spec.masses(rng)
+		r := math.Cbrt(3*m/(4*math.Pi*planetesimalDensity)) * spec.inflate
+		world.bodies = append(world.bodies, body.NewOrbitingBody(fmt.Sprintf("D%v", i), sun, el, r, m,
+			randomPlanetSprite(rng)))
+		total += m
+		lightest, heaviest = math.Min(lightest, m), math.Max(heaviest, m)
+	}
+
+	inner := body.Elements{A: spec.inner}
+	world.dt = inner.Period(G*sun.Mass) / 400
+	world.fitToScreen()
+	fmt.Printf("%v\n", sun)
+	fmt.Printf("Disk of %v planetesimals from %.3g to %.3g au, total mass %.4g kg, masses %.3g to %.3g kg\n",
+		spec.n, spec.inner/au, spec.outer/au, total, lightest, heaviest)
+	return world
+}
+
+// Parse a gas drag spec "epstein,eta=<eta>,tau=<s/m>" for a disk around star
+func parseGasDrag(spec string, star *body.Body) (*body.GasDrag, error) {
+	kind, params, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSpec("gas drag", kind, params, map[string][]string{"epstein": {"eta", "tau"}}); err != nil {
+		return nil, err
+	}
+	if params["tau"] <= 0 {
+		return nil, fmt.Errorf("gas drag epstein requires tau > 0")
+	}
+	eta, ok := params["eta"]
+	if !ok {
+		eta = 0.002
+	}
+	return &body.GasDrag{Star: star, Eta: eta, Tau: params["tau"]}, nil
+}
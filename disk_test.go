@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProtoplanetaryDisk(t *testing.T) {
+	masses, err := parseMassSpectrum("power,q=1.8,min=1e20,max=1e23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resetSprites()
+	spec := diskSpec{n: 500, inner: 0.5 * au, outer: 3 * au, surface: 1.5, masses: masses, rayleighE: 0.01, inflate: 1}
+	world := protoplanetaryDisk(1024, 1024, spec, newRand(1))
+	if len(world.bodies) != 501 {
+		t.Fatalf("expected the star and 500 planetesimals, got %v bodies", len(world.bodies))
+	}
+	sun := world.bodies[0]
+	inner := 0
+	for _, b := range world.bodies[1:] {
+		if b.Mass < 1e20 || b.Mass > 1e23 {
+			t.Errorf("%v has mass %v outside the spectrum", b.Name, b.Mass)
+		}
+		// Eccentricities are a few percent, so bodies stay near the disk
+		r := b.Pos.DistanceTo(sun.Pos)
+		if r < 0.4*au || r > 3.5*au {
+			t.Errorf("%v at %v au outside the disk", b.Name, r/au)
+		}
+		if r < 1.75*au {
+			inner++
+		}
+	}
+	// With p = 1.5 the number of bodies inside r grows as sqrt(r)
+	want := 500 * (math.Sqrt(1.75) - math.Sqrt(0.5)) / (math.Sqrt(3) - math.Sqrt(0.5))
+	if math.Abs(float64(inner)-want) > 40 {
+		t.Errorf("%v bodies inside 1.75 au, expected about %.0f", inner, want)
+	}
+
+	logNormal, err := parseMassSpectrum("lognormal,m=1e21,sigma=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := logNormal(newRand(1)); m != 1e21 {
+		t.Errorf("lognormal with sigma 0 gave %v", m)
+	}
+	for _, bad := range []string{"power,q=1.8,min=1e23,max=1e20", "lognormal,m=-1,sigma=1", "salpeter"} {
+		if _, err := parseMassSpectrum(bad); err == nil {
+			t.Errorf("%q should be an error", bad)
+		}
+	}
+	drag, err := parseGasDrag("epstein,tau=100", sun)
+	if err != nil || drag.Eta != 0.002 || drag.Tau != 100 {
+		t.Errorf("unexpected gas drag %v, %v", drag, err)
+	}
+	if _, err := parseGasDrag("epstein,eta=0.002", sun); err == nil {
+		t.Errorf("gas drag without tau should be an error")
+	}
+}
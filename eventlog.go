@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	"os"
+	"time"
+)
+
+// Bodies closer than this many times the sum of their radii are having a
+// close approach
+const approachFactor = 3.0
+
+// Fields every line of the event log starts with
+type logHeader struct {
+	Event string `json:"event"`
+	// simulated seconds
+	Time float64   `json:"t"`
+	Wall time.Time `json:"wall"`
+}
+
+func (h *logHeader) head() *logHeader {
+	return h
+}
+
+// A line of the event log
+type logEntry interface {
+	head() *logHeader
+}
+
+type loggedBody struct {
+	Id           uint64        `json:"id"`
+	Name         string        `json:"name"`
+	Mass         float64       `json:"mass"`
+	Radius       float64       `json:"radius"`
+	Pos          vector.Vector `json:"pos"`
+	Vel          vector.Vector `json:"vel"`
+	TestParticle bool          `json:"test_particle,omitempty"`
+}
+
+func logBody(b *body.Body) loggedBody {
+	return loggedBody{b.Id, b.Name, b.Mass, b.Radius, b.Pos, b.Vel, b.TestParticle}
+}
+
+type worldEntry struct {
+	logHeader
+	Mode       string       `json:"mode,omitempty"`
+	Resumed    string       `json:"resumed,omitempty"`
+	Seed       int64        `json:"seed"`
+	Law        string       `json:"law"`
+	Potentials []string     `json:"potentials,omitempty"`
+	Dt         float64      `json:"dt"`
+	Bodies     []loggedBody `json:"bodies"`
+}
+
+type collisionEntry struct {
+	logHeader
+	// the body after absorbing the other
+	Into     loggedBody `json:"into"`
+	Absorbed loggedBody `json:"absorbed"`
+}
+
+type escapeEntry struct {
+	logHeader
+	Body loggedBody `json:"body"`
+}
+
+type approachEntry struct {
+	logHeader
+	A        loggedBody `json:"a"`
+	B        loggedBody `json:"b"`
+	Distance float64    `json:"distance"`
+	Speed    float64    `json:"speed"`
+}
+
+type checkpointEntry struct {
+	logHeader
+	File string `json:"file"`
+}
+
+type diagnosticsEntry struct {
+	logHeader
+	Bodies          int     `json:"bodies"`
+	Energy          float64 `json:"energy"`
+	EnergyError     float64 `json:"energy_error"`
+	AngularMomentum float64 `json:"angular_momentum"`
+	StepsPerTick    int     `json:"steps_per_tick"`
+	Dt              float64 `json:"dt"`
+	// simulated seconds run per wall clock second since the last
+	// diagnostics
+	Speed float64 `json:"speed"`
+}
+
+type endEntry struct {
+	logHeader
+	Bodies  int `json:"bodies"`
+	Mergers int `json:"mergers"`
+}
+
+// A pair of bodies within approachFactor of each other, and the closest
+// they have come with their relative speed then
+type approach struct {
+	distance float64
+	speed    float64
+	reported bool
+	// close in the current step
+	seen bool
+}
+
+// Writes what happens in a run to a JSON Lines file: the world as created,
+// collisions, escapes, close approaches, checkpoints and diagnostics every
+// so many simulated seconds
+type eventLog struct {
+	file   *os.File
+	out    *bufio.Writer
+	enc    *json.Encoder
+	failed bool
+
+	every      float64
+	next       float64
+	lastWall   time.Time
+	lastTime   float64
+	energy     float64
+	approaches map[[2]uint64]*approach
+}
+
+// Start the event log at path with an entry for the world
+func newEventLog(w *World, path string, every float64, mode, resumed string) (*eventLog, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	out := bufio.NewWriter(file)
+	l := &eventLog{file: file, out: out, enc: json.NewEncoder(out), every: every, next: w.elapsed + every,
+		lastWall: time.Now(), lastTime: w.elapsed, energy: w.energy(), approaches: map[[2]uint64]*approach{}}
+	e := &worldEntry{Mode: mode, Resumed: resumed, Seed: w.seed, Dt: w.timestep()}
+	if e.Law, err = forceLawSpec(w.forceLaw()); err != nil {
+		e.Law = fmt.Sprint(w.forceLaw())
+	}
+	for _, p := range w.potentials {
+		if spec, err := potentialSpec(p); err == nil {
+			e.Potentials = append(e.Potentials, spec)
+		}
+	}
+	for _, b := range w.bodies {
+		e.Bodies = append(e.Bodies, logBody(b))
+	}
+	l.write(w, "world", e)
+	return l, nil
+}
+
+// Write an entry, giving up on the log after the first failure
+func (l *eventLog) write(w *World, event string, e logEntry) {
+	if l.failed {
+		return
+	}
+	h := e.head()
+	h.Event, h.Time, h.Wall = event, w.elapsed, time.Now()
+	if err := l.enc.Encode(e); err != nil {
+		fmt.Printf("Unable to write event log, stopping: %v\n", err)
+		l.failed = true
+	}
+}
+
+// Log an entry when logging
+func (w *World) logEvent(event string, e logEntry) {
+	if w.events != nil {
+		w.events.write(w, event, e)
+	}
+}
+
+// Log diagnostics if they are due
+func (l *eventLog) diagnose(w *World) {
+	if w.elapsed < l.next {
+		return
+	}
+	l.next = w.elapsed + l.every
+	now := time.Now()
+	e := &diagnosticsEntry{Bodies: len(w.bodies), Energy: w.energy(), AngularMomentum: w.angularMomentum().Magnitude(),
+		StepsPerTick: w.spt, Dt: w.timestep()}
+	if l.energy != 0 {
+		e.EnergyError = (e.Energy - l.energy) / l.energy
+	}
+	if wall := now.Sub(l.lastWall).Seconds(); wall > 0 {
+		e.Speed = (w.elapsed - l.lastTime) / wall
+	}
+	l.lastWall, l.lastTime = now, w.elapsed
+	l.write(w, "diagnostics", e)
+	// Keep the file current for whoever is following it
+	if err := l.out.Flush(); err != nil && !l.failed {
+		fmt.Printf("Unable to write event log, stopping: %v\n", err)
+		l.failed = true
+	}
+}
+
+// Note a body and a massive one that did not collide this step, logging
+// the closest point of a close approach as the two start moving apart
+func (l *eventLog) pair(w *World, a, b *body.Body) {
+	// Pairs of massive bodies come both ways round, take them once
+	if !a.TestParticle && b.Id < a.Id {
+		return
+	}
+	dx, dy, dz := a.Pos.X-b.Pos.X, a.Pos.Y-b.Pos.Y, a.Pos.Z-b.Pos.Z
+	r := approachFactor * (a.Radius + b.Radius)
+	d2 := dx*dx + dy*dy + dz*dz
+	if d2 > r*r {
+		return
+	}
+	d := math.Sqrt(d2)
+	speed := vector.Sub(a.Vel, b.Vel).Magnitude()
+	key := [2]uint64{a.Id, b.Id}
+	near := l.approaches[key]
+	if near == nil {
+		l.approaches[key] = &approach{distance: d, speed: speed, seen: true}
+		return
+	}
+	near.seen = true
+	if d < near.distance {
+		near.distance, near.speed = d, speed
+	} else if !near.reported {
+		near.reported = true
+		l.write(w, "approach", &approachEntry{A: logBody(a), B: logBody(b), Distance: near.distance,
+			Speed: near.speed})
+	}
+}
+
+// Forget the pairs that were not close this step
+func (l *eventLog) endStep() {
+	for key, near := range l.approaches {
+		if !near.seen {
+			delete(l.approaches, key)
+		}
+		near.seen = false
+	}
+}
+
+// Forget the pairs of a body leaving the world
+func (l *eventLog) forget(id uint64) {
+	for key := range l.approaches {
+		if key[0] == id || key[1] == id {
+			delete(l.approaches, key)
+		}
+	}
+}
+
+// Log the end of the run and close the log
+func (w *World) closeLog(path string) {
+	if w.events == nil {
+		return
+	}
+	w.logEvent("end", &endEntry{Bodies: len(w.bodies), Mergers: len(w.mergers)})
+	err := w.events.out.Flush()
+	if closeErr := w.events.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.events = nil
+	if err != nil {
+		fmt.Printf("Unable to write event log: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote the event log to %v\n", path)
+}
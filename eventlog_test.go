@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/seifertd/nbody-go/body"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLog(t *testing.T) {
+	resetSprites()
+	world := randomWorld(1024, 1024, 40, 0.5, 0.3, orbitSpread{0.2, 0.1}, newRand(7))
+	world.seed, world.spt = 7, 20
+	path := t.TempDir() + "/events.jsonl"
+	var err error
+	if world.events, err = newEventLog(world, path, 100*world.timestep(), "random", ""); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		world.tick()
+	}
+	live := map[uint64]bool{}
+	for _, b := range world.bodies {
+		live[b.Id] = true
+	}
+	for key := range world.events.approaches {
+		if !live[key[0]] || !live[key[1]] {
+			t.Errorf("close approach of a removed body still tracked: %v", key)
+		}
+	}
+	world.closeLog(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	var lines []map[string]any
+	last := -1.0
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("%v: %v", line, err)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, entry["wall"].(string)); err != nil {
+			t.Errorf("bad wall clock time: %v", err)
+		}
+		if entry["t"].(float64) < last {
+			t.Errorf("%v logged at %v after %v", entry["event"], entry["t"], last)
+		}
+		last = entry["t"].(float64)
+		counts[entry["event"].(string)]++
+		lines = append(lines, entry)
+	}
+	if lines[0]["event"] != "world" || len(lines[0]["bodies"].([]any)) != 41 || lines[0]["seed"] != 7.0 {
+		t.Errorf("first entry should describe the world: %v", lines[0])
+	}
+	if end := lines[len(lines)-1]; end["event"] != "end" || end["bodies"] != float64(len(world.bodies)) {
+		t.Errorf("last entry should end the run: %v", end)
+	}
+	// 400 steps, diagnostics every 100
+	if counts["diagnostics"] != 4 || counts["collision"] != len(world.mergers) || counts["collision"] == 0 ||
+		counts["approach"] == 0 {
+		t.Errorf("logged %v for %v mergers", counts, len(world.mergers))
+	}
+
+	// An approach is logged with the distance and speed of its closest point
+	a := body.NewBody("A", 0, 0, 1, 1, 0, 0, nil)
+	b := body.NewBody("B", 0, 0, 1, 1, 0, 0, nil)
+	world = &World{bodies: []*body.Body{a, b}}
+	if world.events, err = newEventLog(world, path, 1e9, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct{ x, vx float64 }{{5, -3}, {2.5, -1}, {4, 2}} {
+		b.Pos.X, b.Vel.X = step.x, step.vx
+		world.events.pair(world, a, b)
+		world.events.endStep()
+	}
+	world.closeLog(path)
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var near approachEntry
+	if err := json.Unmarshal([]byte(strings.Split(string(data), "\n")[1]), &near); err != nil {
+		t.Fatal(err)
+	}
+	if near.Event != "approach" || near.Distance != 2.5 || near.Speed != 1 {
+		t.Errorf("approach should be logged at its closest point: %+v", near)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	"sort"
+)
+
+const (
+	// A flyby changed an orbit significantly when its semi-major axis
+	// changed by this fraction, or its eccentricity or inclination (in
+	// radians) by this much
+	flybyChangeA   = 0.1
+	flybyChangeE   = 0.05
+	flybyChangeInc = 5 * math.Pi / 180
+)
+
+// Orbit of a body around its host before the flyby
+type flybyOrbit struct {
+	name string
+	host *body.Body
+	el   body.Elements
+}
+
+// A star passing through the world on a hyperbolic orbit, with the orbits
+// of the other bodies from before it arrived
+type flyby struct {
+	star    *body.Body
+	primary *body.Body
+	// distance from the primary the star started at
+	start  float64
+	before map[uint64]flybyOrbit
+	// bodies removed by escaping, including the star itself
+	escaped  map[uint64]bool
+	reported bool
+}
+
+// Parse a flyby spec "star,m=<kg>,b=<m>,v=<m/s>,inc=<deg>,node=<deg>,peri=<deg>,d=<m>":
+// the star's mass, impact parameter and velocity at infinity relative to
+// the world's barycenter, the orientation of its orbit and the distance it
+// starts at, by default 10 times the larger of b and the world's extent.
+// b and v are required.
+func parseFlyby(spec string) (map[string]float64, error) {
+	kind, params, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSpec("flyby", kind, params, map[string][]string{
+		"star": {"m", "b", "v", "inc", "node", "peri", "d"}}); err != nil {
+		return nil, err
+	}
+	if params["b"] <= 0 || params["v"] <= 0 {
+		return nil, fmt.Errorf("flyby star requires b > 0 and v > 0")
+	}
+	if _, ok := params["m"]; !ok {
+		params["m"] = solarBodies[0].mass
+	}
+	if params["m"] <= 0 || params["d"] < 0 {
+		return nil, fmt.Errorf("flyby star requires m > 0 and d >= 0")
+	}
+	return params, nil
+}
+
+// Add a star on the incoming branch of a hyperbolic orbit around the
+// world's barycenter, and remember the orbits it may disturb
+func addFlyby(w *World, params map[string]float64) (*flyby, error) {
+	if len(w.bodies) == 0 {
+		return nil, fmt.Errorf("no bodies to fly by")
+	}
+	center, centerVel := w.barycenter()
+	mass := 0.0
+	extent := 0.0
+	for _, b := range w.bodies {
+		mass += b.Mass
+		extent = math.Max(extent, b.Pos.DistanceTo(center))
+	}
+	m, b, v := params["m"], params["b"], params["v"]
+	d := params["d"]
+	if d == 0 {
+		d = 10 * math.Max(b, extent)
+	}
+	mu := G * (mass + m)
+	rad := math.Pi / 180
+	el := body.Elements{A: -mu / (v * v), I: params["inc"] * rad, Node: params["node"] * rad, Peri: params["peri"] * rad}
+	el.E = math.Sqrt(1 + b*b*v*v*v*v/(mu*mu))
+	// Hyperbolic anomaly at distance d, negative before periapsis
+	f := -math.Acosh(math.Max(1, (1-d/el.A)/el.E))
+	el.M = el.E*math.Sinh(f) - f
+
+	fb := &flyby{start: d, before: map[uint64]flybyOrbit{}, escaped: map[uint64]bool{}}
+	fb.primary, _ = w.heaviestPair()
+	for _, o := range w.bodies {
+		if host := flybyHost(w, o); host != nil {
+			fb.before[o.Id] = flybyOrbit{o.Name, host, fb.elements(o, host)}
+		}
+	}
+	barycenter := body.NewBodyVector("barycenter", center, centerVel, 0, mass, nil)
+	fb.star = body.NewOrbitingBody("Flyby", barycenter, el, solarBodies[0].radius*math.Cbrt(m/solarBodies[0].mass), m,
+		sprites["sun"])
+	w.bodies = append(w.bodies, fb.star)
+	fmt.Printf("%v\n", fb.star)
+	fmt.Printf("Flyby of %.4g kg: impact parameter %.4g m, v at infinity %.4g m/s, e %.4g, periapsis %.4g m, starting %.4g m away\n",
+		m, b, v, el.E, el.A*(1-el.E), d)
+	return fb, nil
+}
+
+// Send a star through a world, unless a resumed world still has one passing
+// through
+func startFlyby(w *World, params map[string]float64) error {
+	if w.flyby != nil && !w.flyby.reported {
+		return fmt.Errorf("the world already has a flyby in progress")
+	}
+	fb, err := addFlyby(w, params)
+	if err != nil {
+		return err
+	}
+	w.flyby = fb
+	return nil
+}
+
+// The nearest heavier body that b is bound to, so moons are followed around
+// their planet. Nil for the heaviest body and anything unbound.
+func flybyHost(w *World, b *body.Body) *body.Body {
+	var host *body.Body
+	for _, o := range w.bodies {
+		if o == b || o.TestParticle || o.Mass <= b.Mass {
+			continue
+		}
+		if (host == nil || b.Pos.DistanceTo(o.Pos) < b.Pos.DistanceTo(host.Pos)) &&
+			body.ElementsOf(vector.Sub(b.Pos, o.Pos), vector.Sub(b.Vel, o.Vel), G*(o.Mass+b.Mass)).E < 1 {
+			host = o
+		}
+	}
+	return host
+}
+
+// Elements of b around host
+func (f *flyby) elements(b, host *body.Body) body.Elements {
+	return body.ElementsOf(vector.Sub(b.Pos, host.Pos), vector.Sub(b.Vel, host.Vel), G*(host.Mass+b.Mass))
+}
+
+// Report once the star has passed and is farther away than it started, or
+// has escaped
+func (f *flyby) check(w *World) {
+	if f.reported {
+		return
+	}
+	rel := vector.Sub(f.star.Pos, f.primary.Pos)
+	receding := rel.Dot(vector.Sub(f.star.Vel, f.primary.Vel)) > 0
+	if f.escaped[f.star.Id] || (receding && rel.Magnitude() > f.start) {
+		f.report(w)
+	}
+}
+
+// What a flyby did to a body
+type flybyOutcome struct {
+	name    string
+	outcome string
+}
+
+// What the flyby did to each body that was there before it, by id since
+// generated bodies share names
+func (f *flyby) outcomes(w *World) map[uint64]flybyOutcome {
+	present := map[uint64]*body.Body{}
+	for _, b := range w.bodies {
+		present[b.Id] = b
+	}
+	outcomes := map[uint64]flybyOutcome{}
+	for id, orbit := range f.before {
+		before := orbit.el
+		outcome := ""
+		b, ok := present[id]
+		switch {
+		case f.escaped[id]:
+			outcome = "ejected"
+		case !ok:
+			outcome = "merged"
+		case present[orbit.host.Id] == nil:
+			outcome = fmt.Sprintf("lost its host %v", orbit.host.Name)
+		default:
+			after := f.elements(b, orbit.host)
+			star := f.elements(b, f.star)
+			toStar := b.Pos.DistanceTo(f.star.Pos) < b.Pos.DistanceTo(orbit.host.Pos)
+			if present[f.star.Id] != nil && star.E < 1 && (after.E >= 1 || toStar) {
+				outcome = "captured"
+			} else if after.E >= 1 {
+				outcome = "unbound"
+			} else if math.Abs(after.A-before.A) > flybyChangeA*math.Abs(before.A) ||
+				math.Abs(after.E-before.E) > flybyChangeE || math.Abs(after.I-before.I) > flybyChangeInc {
+				outcome = fmt.Sprintf("changed: a %.4g -> %.4g, e %.3f -> %.3f, i %.2f -> %.2f deg",
+					before.A, after.A, before.E, after.E, before.I*180/math.Pi, after.I*180/math.Pi)
+			}
+		}
+		if outcome != "" {
+			outcomes[id] = flybyOutcome{orbit.name, outcome}
+		}
+	}
+	return outcomes
+}
+
+// Print the outcome of the flyby for every body it affected
+func (f *flyby) report(w *World) {
+	f.reported = true
+	outcomes := f.outcomes(w)
+	ids := make([]uint64, 0, len(outcomes))
+	for id := range outcomes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := outcomes[ids[i]], outcomes[ids[j]]
+		return a.name < b.name || (a.name == b.name && ids[i] < ids[j])
+	})
+	fmt.Printf("%v: FLYBY: %v of %v bodies affected\n", w.worldTime(), len(ids), len(f.before))
+	for _, id := range ids {
+		fmt.Printf("%v: FLYBY: %v (#%v) %v\n", w.worldTime(), outcomes[id].name, id, outcomes[id].outcome)
+	}
+}
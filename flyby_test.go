@@ -0,0 +1,134 @@
+package main
+
+import (
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFlyby(t *testing.T) {
+	resetSprites()
+	newWorld := func() *World {
+		world := &World{width: 1024, height: 1024, mag: 1, scale: 1, spt: 1, running: true}
+		sun := body.NewBody("Sol", 0, 0, 7e8, 2e30, 0, 0, nil)
+		world.bodies = []*body.Body{sun}
+		// Planets share a name like generated bodies do
+		for i, a := range []float64{1, 2, 3, 4, 5} {
+			world.bodies = append(world.bodies, body.NewOrbitingBody("Planet", sun,
+				body.Elements{A: a * au, M: float64(i)}, 6e6, 6e24, nil))
+		}
+		moon := body.NewOrbitingBody("M", world.bodies[2], body.Elements{A: 1e9}, 1e6, 1e22, nil)
+		world.bodies = append(world.bodies, moon)
+		world.fitToScreen()
+		world.dt = body.Elements{A: au}.Period(G*2e30) / 1000
+		return world
+	}
+
+	params, err := parseFlyby("star,b=1.5e12,v=3000,inc=30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	world := newWorld()
+	fb, err := addFlyby(world, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The star starts on a hyperbola with the requested v at infinity and
+	// impact parameter about the system's barycenter
+	mass := 2e30 + 5*6e24 + 1e22
+	rel := vector.Sub(fb.star.Pos, world.bodies[0].Pos)
+	relVel := vector.Sub(fb.star.Vel, world.bodies[0].Vel)
+	if e := relVel.Dot(relVel)/2 - G*(mass+fb.star.Mass)/rel.Magnitude(); math.Abs(e-4.5e6) > 1e-3*4.5e6 {
+		t.Errorf("specific energy %v, expected %v", e, 4.5e6)
+	}
+	if rel.Dot(relVel) >= 0 || fb.star.Mass != solarBodies[0].mass {
+		t.Errorf("star should be incoming with a solar mass")
+	}
+	if fb.before[world.bodies[6].Id].host != world.bodies[2] {
+		t.Errorf("the moon's host should be its planet")
+	}
+	if len(fb.outcomes(world)) != 0 {
+		t.Errorf("nothing should be affected before the flyby: %v", fb.outcomes(world))
+	}
+
+	// Outcomes after disturbing the bodies by hand
+	star := fb.star
+	p := append([]*body.Body{}, world.bodies...)
+	p[1].Vel.MultScalar(2)
+	fb.escaped[p[3].Id] = true
+	world.removeBody(p[3])
+	p[4].Pos = vector.Add(star.Pos, vector.Vector{1e10, 0, 0})
+	p[4].Vel = vector.Add(star.Vel, vector.Vector{0, math.Sqrt(G * star.Mass / 1e10), 0})
+	p[5].Vel.MultScalar(1.1)
+	got := fb.outcomes(world)
+	for i, want := range map[int]string{1: "unbound", 3: "ejected", 4: "captured", 5: "changed"} {
+		if o := got[p[i].Id]; o.name != "Planet" || !strings.HasPrefix(o.outcome, want) {
+			t.Errorf("planet %v should be %v: %v", i, want, o)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("only four bodies should be affected: %v", got)
+	}
+
+	// A close passage through the inner system gets reported once the star
+	// is on its way out
+	world = newWorld()
+	params, _ = parseFlyby("star,m=2e30,b=1.5e11,v=30000,d=1.5e12")
+	world.flyby, _ = addFlyby(world, params)
+	for i := 0; i < 5000 && !world.flyby.reported; i++ {
+		world.tick()
+	}
+	if !world.flyby.reported || len(world.flyby.outcomes(world)) == 0 {
+		t.Errorf("flyby should have been reported with affected bodies")
+	}
+
+	for _, bad := range []string{"star,b=1e12", "star,v=1e3", "star,b=1e12,v=1e3,x=1", "planet,b=1,v=1"} {
+		if _, err := parseFlyby(bad); err == nil {
+			t.Errorf("%q should be an error", bad)
+		}
+	}
+}
+
+// The world is moved to its barycenter before the star is placed around it,
+// in a generated world as in a resumed one
+func TestFlybySetUp(t *testing.T) {
+	resetSprites()
+	world := &World{width: 1024, height: 1024, mag: 1, scale: 1, spt: 1, running: true}
+	sun := body.NewBody("Sol", 2*au, -au, 7e8, 2e30, 5000, 2000, nil)
+	world.bodies = []*body.Body{sun, body.NewOrbitingBody("P", sun, body.Elements{A: 5 * au}, 6e6, 2e27, nil)}
+	world.fitToScreen()
+	world.dt = 3600
+	params, err := parseFlyby("star,b=1.5e12,v=3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := setUpWorld(world, nil, false, params); err != nil {
+		t.Fatal(err)
+	}
+	system := &World{bodies: world.bodies[:2]}
+	if pos, vel := system.barycenter(); pos.Magnitude() > 1 || vel.Magnitude() > 1e-9 {
+		t.Errorf("the system should stay at rest at the origin: %v %v", pos, vel)
+	}
+	if d := world.flyby.star.Pos.Magnitude(); math.Abs(d-world.flyby.start) > 1e-6*d {
+		t.Errorf("star should start %v from the barycenter: %v", world.flyby.start, d)
+	}
+
+	// A resumed world gets a new star only once the last one has passed
+	path := t.TempDir() + "/checkpoint.json"
+	if err := world.saveCheckpoint(path, camera{Follow: -1}); err != nil {
+		t.Fatal(err)
+	}
+	resumed, _, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := startFlyby(resumed, params); err == nil {
+		t.Errorf("a second flyby during the first should be an error")
+	}
+	resumed.flyby.reported = true
+	if err := startFlyby(resumed, params); err != nil || len(resumed.bodies) != 4 || resumed.flyby.star != resumed.bodies[3] {
+		t.Errorf("a star should be sent through the resumed world: %v", err)
+	}
+}
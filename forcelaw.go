@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/nbody-go/body"
+	math_rand "math/rand"
+)
+
+// Parse a force law spec of the form kind,key=value,... e.g. "yukawa,lambda=1e9"
+func parseForceLaw(spec string) (body.ForceLaw, float64, error) {
+	kind, params, err := parseSpec(spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	allowed := map[string][]string{
+		"newton":  {},
+		"coulomb": {"q"},
+		"yukawa":  {"lambda"},
+		"mond":    {"a0"},
+		"power":   {"n"},
+	}
+	if err := checkSpec("force law", kind, params, allowed); err != nil {
+		return nil, 0, err
+	}
+	switch kind {
+	case "coulomb":
+		return body.Coulomb{}, params["q"], nil
+	case "yukawa":
+		if params["lambda"] <= 0 {
+			return nil, 0, fmt.Errorf("force law yukawa requires lambda > 0")
+		}
+		return body.Yukawa{Lambda: params["lambda"]}, 0, nil
+	case "mond":
+		a0, ok := params["a0"]
+		if !ok {
+			a0 = 1.2e-10
+		}
+		return body.MOND{A0: a0}, 0, nil
+	case "power":
+		n, ok := params["n"]
+		if !ok {
+			n = 2
+		}
+		return body.PowerLaw{N: n}, 0, nil
+	default:
+		return body.Newtonian{}, 0, nil
+	}
+}
+
+// Spec of a force law that parseForceLaw turns back into it. Charges live
+// on the bodies, so coulomb has no q.
+func forceLawSpec(law body.ForceLaw) (string, error) {
+	switch law := law.(type) {
+	case body.Newtonian:
+		return "newton", nil
+	case body.Coulomb:
+		return "coulomb", nil
+	case body.Yukawa:
+		return fmt.Sprintf("yukawa,lambda=%v", law.Lambda), nil
+	case body.MOND:
+		return fmt.Sprintf("mond,a0=%v", law.A0), nil
+	case body.PowerLaw:
+		return fmt.Sprintf("power,n=%v", law.N), nil
+	}
+	return "", fmt.Errorf("no spec for force law %v", law)
+}
+
+// Whether the world runs under the Coulomb law with no charged body, so
+// that nothing interacts at all
+func unchargedCoulomb(w *World) bool {
+	if _, coulomb := w.forceLaw().(body.Coulomb); !coulomb {
+		return false
+	}
+	for _, b := range w.bodies {
+		if b.Charge != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Give every uncharged massive body a charge of +q or -q at random
+func assignCharges(world *World, q float64, rng *math_rand.Rand) {
+	for _, b := range world.bodies {
+		if b.Charge == 0 && !b.TestParticle {
+			b.Charge = q
+			if rng.Intn(2) == 1 {
+				b.Charge = -q
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestUnchargedCoulomb(t *testing.T) {
+	resetSprites()
+	world := randomWorld(1024, 1024, 10, 0.5, 0.3, orbitSpread{}, newRand(1))
+	if unchargedCoulomb(world) {
+		t.Errorf("gravity needs no charges")
+	}
+	law, q, err := parseForceLaw("coulomb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	world.law = law
+	if !unchargedCoulomb(world) {
+		t.Errorf("coulomb without charges should be caught")
+	}
+	assignCharges(world, 1e-3, newRand(1))
+	if q != 0 || unchargedCoulomb(world) {
+		t.Errorf("charged bodies interact")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	math_rand "math/rand"
+)
+
+const (
+	// Mass of each galaxy's central body, about 5e10 solar masses
+	galaxyMass = 1e41
+	// Disk scale length, 3 kpc
+	diskScale = 9.26e19
+	// Disk particles are kept between these radii in scale lengths
+	diskInner = 0.3
+	diskOuter = 5.0
+	// Closest approach and starting separation of the encounter in scale
+	// lengths
+	galaxyPericenter = 3.0
+	galaxySeparation = 14.0
+)
+
+// Radius of a particle in an exponential disk: the surface density falls as
+// exp(-r/scale), so r is gamma distributed with shape 2. Radii outside
+// [inner, outer] scale lengths are redrawn.
+func exponentialDiskRadius(scale, inner, outer float64, rng *math_rand.Rand) float64 {
+	for {
+		r := -scale * math.Log((1-rng.Float64())*(1-rng.Float64()))
+		if r >= inner*scale && r <= outer*scale {
+			return r
+		}
+	}
+}
+
+// Add n test particles on circular orbits around center in a disk with
+// the given inclination and node.
+func addDisk(world *World, center *body.Body, n int, inc, node float64, rng *math_rand.Rand) {
+	for i := 0; i < n; i++ {
+		el := body.Elements{
+			A:    exponentialDiskRadius(diskScale, diskInner, diskOuter, rng),
+			I:    inc,
+			Node: node,
+			M:    rng.Float64() * math.Pi * 2,
+		}
+		world.bodies = append(world.bodies, body.NewOrbitingTestParticle(fmt.Sprintf("%vT%v", center.Name, i),
+			center, el, diskScale/100, sprites["circle"]))
+	}
+}
+
+// Relative position and velocity on a parabolic orbit with pericenter q at
+// separation d, before pericenter. Elements cannot describe e = 1, so this
+// uses the true anomaly directly.
+func parabolicApproach(q, d, mu float64) (vector.Vector, vector.Vector) {
+	p := 2 * q
+	nu := -math.Acos(p/d - 1)
+	v := math.Sqrt(mu / p)
+	return vector.Vector{d * math.Cos(nu), d * math.Sin(nu), 0},
+		vector.Vector{-v * math.Sin(nu), v * (1 + math.Cos(nu)), 0}
+}
+
+// Two equal disk galaxies, each a central body with n test particles, on a
+// parabolic encounter in the xy plane. The first disk lies in the orbital
+// plane and the second is tilted by inc radians; inclinations above 90
+// degrees make it spin against the orbit.
+func galaxyCollision(w, h, n int, inc float64, rng *math_rand.Rand) *World {
+	world := &World{
+		scale:   1.0,
+		spt:     5,
+		running: true,
+		elapsed: 0,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+	}
+	rel, relVel := parabolicApproach(galaxyPericenter*diskScale, galaxySeparation*diskScale, G*2*galaxyMass)
+	g1 := body.NewBodyVector("G1", vector.MultScalar(rel, -0.5), vector.MultScalar(relVel, -0.5),
+		diskScale/10, galaxyMass, sprites["sun"])
+	g2 := body.NewBodyVector("G2", vector.MultScalar(rel, 0.5), vector.MultScalar(relVel, 0.5),
+		diskScale/10, galaxyMass, sprites["sun"])
+	world.bodies = []*body.Body{g1, g2}
+	addDisk(world, g1, n, 0, 0, rng)
+	addDisk(world, g2, n, inc, 0, rng)
+
+	// Resolve the innermost orbits
+	inner := body.Elements{A: diskInner * diskScale}
+	world.dt = inner.Period(G*galaxyMass) / 200
+	world.fitToScreen()
+	fmt.Printf("%v\n%v\n", g1, g2)
+	fmt.Printf("Two disks of %v test particles, second disk inclined %.1f degrees\n", n, inc*180/math.Pi)
+	return world
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	"testing"
+)
+
+func TestGalaxyCollision(t *testing.T) {
+	resetSprites()
+	world := galaxyCollision(1024, 1024, 100, math.Pi/3, newRand(1))
+	if len(world.bodies) != 202 {
+		t.Fatalf("expected 2 centers and 200 particles: %v", len(world.bodies))
+	}
+	g1, g2 := world.bodies[0], world.bodies[1]
+	rel := vector.Sub(g2.Pos, g1.Pos)
+	relVel := vector.Sub(g2.Vel, g1.Vel)
+	// Parabolic: kinetic and potential energy of the relative orbit cancel
+	mu := G * (g1.Mass + g2.Mass)
+	if e := relVel.Dot(relVel)/2 - mu/rel.Magnitude(); math.Abs(e) > 1e-9*mu/rel.Magnitude() {
+		t.Errorf("encounter should be parabolic, specific energy %v", e)
+	}
+	if rel.Dot(relVel) >= 0 {
+		t.Errorf("galaxies should be approaching")
+	}
+	for _, b := range world.bodies[2:] {
+		center := g1
+		tilt := 0.0
+		if b.Name[:2] == "G2" {
+			center, tilt = g2, math.Pi/3
+		}
+		r := vector.Sub(b.Pos, center.Pos)
+		v := vector.Sub(b.Vel, center.Vel)
+		h := body.Cross(r, v)
+		if !b.TestParticle || math.Abs(math.Acos(h.Z/h.Magnitude())-tilt) > 1e-9 {
+			t.Errorf("%v should be a test particle in its disk plane: %v", b.Name, h)
+		}
+		if d := r.Magnitude(); d < diskInner*diskScale || d > diskOuter*diskScale {
+			t.Errorf("%v outside the disk: %v", b.Name, d)
+		}
+	}
+}
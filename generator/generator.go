@@ -0,0 +1,290 @@
+// Package generator is a registry of world generators: named recipes for
+// initial conditions with typed, documented parameters. Generators outside
+// this module implement ScenarioGenerator, building a scenario.Scenario
+// that the viewer runs like a scenario file, and register themselves from
+// an init function like database/sql drivers:
+//
+//	func init() {
+//		generator.MustRegister(ring{})
+//	}
+//
+// A program picks them up with a blank import of the registering package.
+package generator
+
+import (
+	"fmt"
+	"github.com/seifertd/nbody-go/scenario"
+	math_rand "math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kind is the type of a parameter's value
+type Kind int
+
+const (
+	Int Kind = iota
+	Float
+	String
+	// Bool parameters are flags without a value
+	Bool
+)
+
+func (k Kind) String() string {
+	return [...]string{"int", "float", "string", "bool"}[k]
+}
+
+// Param is one parameter of a generator, given on the command line as
+// --Name=<Meta>, or -Short=<Meta> when the parameter has no long name.
+// Parameters without a default are optional and empty when not given.
+type Param struct {
+	Name    string
+	Short   string
+	Kind    Kind
+	Meta    string
+	Default string
+	Usage   string
+}
+
+// Key the parameter's value is stored under: its long name, or its short
+// one without a long name
+func (p Param) Key() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Short
+}
+
+// Option is the command line option for the parameter, the long one when
+// there is one
+func (p Param) Option() string {
+	if p.Name != "" {
+		return "--" + p.Name
+	}
+	return "-" + p.Short
+}
+
+// Synopsis of the option in docopt form, e.g. "-n=<n>, --number=<n>"
+func (p Param) Synopsis() string {
+	var forms []string
+	if p.Short != "" {
+		forms = append(forms, "-"+p.Short)
+	}
+	if p.Name != "" {
+		forms = append(forms, "--"+p.Name)
+	}
+	if p.Kind != Bool {
+		for i := range forms {
+			forms[i] += "=<" + p.Meta + ">"
+		}
+	}
+	return strings.Join(forms, ", ")
+}
+
+// Spec describes a generator for the registry and its help
+type Spec struct {
+	Name        string
+	Description string
+	Params      []Param
+}
+
+// Generator is anything in the registry. The viewer's built in generators
+// build its worlds directly, others implement ScenarioGenerator.
+type Generator interface {
+	Spec() Spec
+}
+
+// ScenarioGenerator builds initial conditions from its parameter values.
+// The random source is seeded by the caller so runs can be reproduced.
+type ScenarioGenerator interface {
+	Generator
+	Generate(values Values, rng *math_rand.Rand) (*scenario.Scenario, error)
+}
+
+// Values are a generator's parameter values, checked against their kinds
+type Values struct {
+	params map[string]Param
+	raw    map[string]string
+}
+
+// Parse checks raw values, by parameter key, against the spec's
+// parameters and fills in the defaults
+func (s Spec) Parse(raw map[string]string) (Values, error) {
+	v := Values{params: map[string]Param{}, raw: map[string]string{}}
+	for _, p := range s.Params {
+		v.params[p.Key()] = p
+		value, ok := raw[p.Key()]
+		if !ok {
+			value = p.Default
+		}
+		if value == "" {
+			continue
+		}
+		var err error
+		switch p.Kind {
+		case Int:
+			_, err = strconv.Atoi(value)
+		case Float:
+			_, err = strconv.ParseFloat(value, 64)
+		case Bool:
+			_, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			return Values{}, fmt.Errorf("%v: %q is not a %v", p.Option(), value, p.Kind)
+		}
+		v.raw[p.Key()] = value
+	}
+	for key := range raw {
+		if _, ok := v.params[key]; !ok {
+			return Values{}, fmt.Errorf("%v does not take parameter %v", s.Name, key)
+		}
+	}
+	return v, nil
+}
+
+func (v Values) check(key string, kind Kind) {
+	p, ok := v.params[key]
+	if !ok || p.Kind != kind {
+		panic(fmt.Sprintf("generator has no %v parameter %v", kind, key))
+	}
+}
+
+// Has tells whether the parameter was given or has a default
+func (v Values) Has(key string) bool {
+	_, ok := v.raw[key]
+	return ok
+}
+
+func (v Values) Int(key string) int {
+	v.check(key, Int)
+	i, _ := strconv.Atoi(v.raw[key])
+	return i
+}
+
+func (v Values) Float(key string) float64 {
+	v.check(key, Float)
+	f, _ := strconv.ParseFloat(v.raw[key], 64)
+	return f
+}
+
+func (v Values) String(key string) string {
+	v.check(key, String)
+	return v.raw[key]
+}
+
+func (v Values) Bool(key string) bool {
+	v.check(key, Bool)
+	b, _ := strconv.ParseBool(v.raw[key])
+	return b
+}
+
+var (
+	mu         sync.Mutex
+	generators = map[string]Generator{}
+	// names in the order they were registered
+	names []string
+)
+
+// Register adds a generator. Names must be unique, and parameters shared
+// with other generators by key must be identical so they can share one
+// command line option.
+func Register(g Generator) error {
+	mu.Lock()
+	defer mu.Unlock()
+	spec := g.Spec()
+	if spec.Name == "" {
+		return fmt.Errorf("generator needs a name")
+	}
+	if _, ok := generators[spec.Name]; ok {
+		return fmt.Errorf("generator %v is already registered", spec.Name)
+	}
+	seen := map[string]bool{}
+	for _, p := range spec.Params {
+		if p.Key() == "" {
+			return fmt.Errorf("generator %v has a parameter without a name", spec.Name)
+		}
+		if seen[p.Key()] {
+			return fmt.Errorf("generator %v has two parameters %v", spec.Name, p.Key())
+		}
+		seen[p.Key()] = true
+		for _, other := range generators {
+			for _, q := range other.Spec().Params {
+				if q.Key() == p.Key() && q != p {
+					return fmt.Errorf("generator %v parameter %v differs from the one of %v", spec.Name, p.Key(),
+						other.Spec().Name)
+				}
+			}
+		}
+	}
+	generators[spec.Name] = g
+	names = append(names, spec.Name)
+	return nil
+}
+
+// MustRegister is Register for init functions, panicking on errors
+func MustRegister(g Generator) {
+	if err := Register(g); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the generator with name, so tests and programs
+// replacing a generator can register it again. It reports whether there was
+// one.
+func Unregister(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := generators[name]; !ok {
+		return false
+	}
+	delete(generators, name)
+	for i, n := range names {
+		if n == name {
+			names = append(names[:i:i], names[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Lookup finds a registered generator by name
+func Lookup(name string) (Generator, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	g, ok := generators[name]
+	return g, ok
+}
+
+// All registered generators in the order they were registered
+func All() []Generator {
+	mu.Lock()
+	defer mu.Unlock()
+	all := make([]Generator, 0, len(names))
+	for _, name := range names {
+		all = append(all, generators[name])
+	}
+	return all
+}
+
+// OptionLine is the parameter's line in a docopt options section
+func OptionLine(p Param, usage string) string {
+	line := fmt.Sprintf("\t%-20v  %v", p.Synopsis(), usage)
+	if p.Default != "" && p.Kind != Bool {
+		line += fmt.Sprintf(" [default: %v]", p.Default)
+	}
+	return line
+}
+
+// Help for one generator: its description and parameters
+func Help(spec Spec) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: %v\n", spec.Name, spec.Description)
+	if len(spec.Params) > 0 {
+		fmt.Fprintf(&b, "Options:\n")
+		for _, p := range spec.Params {
+			fmt.Fprintln(&b, OptionLine(p, p.Usage))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+type spec Spec
+
+func (s spec) Spec() Spec {
+	return Spec(s)
+}
+
+var testParams = []Param{
+	{Name: "count", Short: "c", Kind: Int, Meta: "n", Default: "3", Usage: "Number of things"},
+	{Short: "x", Kind: Float, Meta: "x", Usage: "Scale"},
+	{Name: "shape", Kind: String, Meta: "name", Default: "ring", Usage: "Shape"},
+	{Name: "flat", Kind: Bool, Usage: "Flatten"},
+}
+
+func TestParse(t *testing.T) {
+	s := Spec{Name: "things", Params: testParams}
+	v, err := s.Parse(map[string]string{"count": "5", "flat": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Int("count") != 5 || v.String("shape") != "ring" || !v.Bool("flat") || v.Has("x") || v.Float("x") != 0 {
+		t.Errorf("wrong values %v", v.raw)
+	}
+	if _, err := s.Parse(map[string]string{"x": "wide"}); err == nil || !strings.Contains(err.Error(), "-x") {
+		t.Errorf("float parameter took a string: %v", err)
+	}
+	if _, err := s.Parse(map[string]string{"size": "1"}); err == nil {
+		t.Errorf("unknown parameter accepted")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("reading count as a float did not panic")
+			}
+		}()
+		v.Float("count")
+	}()
+}
+
+func TestRegister(t *testing.T) {
+	t.Cleanup(func() {
+		Unregister("test-a")
+		Unregister("test-c")
+	})
+	if err := Register(spec{Name: "test-a", Params: testParams}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(spec{Name: "test-a"}); err == nil {
+		t.Errorf("registered a name twice")
+	}
+	changed := testParams[0]
+	changed.Default = "4"
+	if err := Register(spec{Name: "test-b", Params: []Param{changed}}); err == nil {
+		t.Errorf("registered a shared parameter with another default")
+	}
+	if err := Register(spec{Name: "test-c", Params: testParams[:2]}); err != nil {
+		t.Errorf("could not share identical parameters: %v", err)
+	}
+	if _, ok := Lookup("test-b"); ok {
+		t.Errorf("failed registration is in the registry")
+	}
+	var names []string
+	for _, g := range All() {
+		names = append(names, g.Spec().Name)
+	}
+	if strings.Join(names, ",") != "test-a,test-c" {
+		t.Errorf("registered generators %v", names)
+	}
+	if !Unregister("test-a") || Unregister("test-a") {
+		t.Errorf("unregistering should remove a generator once")
+	}
+	if len(All()) != 1 || All()[0].Spec().Name != "test-c" {
+		t.Errorf("test-a still registered")
+	}
+}
+
+func TestHelp(t *testing.T) {
+	help := Help(Spec{Name: "things", Description: "some things", Params: testParams})
+	for _, want := range []string{
+		"things: some things\n",
+		"-c=<n>, --count=<n>   Number of things [default: 3]",
+		"-x=<x>                Scale\n",
+		"--flat                Flatten\n",
+	} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help lacks %q:\n%v", want, help)
+		}
+	}
+}
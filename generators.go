@@ -0,0 +1,440 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"github.com/seifertd/nbody-go/generator"
+	"math"
+	math_rand "math/rand"
+	"strings"
+)
+
+// Everything a generator builds a world from
+type genContext struct {
+	width  int
+	height int
+	values generator.Values
+	// scenario file given after the MODE
+	file string
+	rng  *math_rand.Rand
+	// edges of the asteroid belt from --belt, in meters
+	beltInner, beltOuter float64
+}
+
+// A generator built into the viewer, building its World directly
+type builtinGenerator struct {
+	spec  generator.Spec
+	build func(ctx genContext) (*World, error)
+}
+
+func (g builtinGenerator) Spec() generator.Spec {
+	return g.spec
+}
+
+// Parameters shared by several generators
+var (
+	paramNumber = generator.Param{Name: "number", Short: "n", Kind: generator.Int, Meta: "numBodies", Default: "60",
+		Usage: "Number of bodies to start"}
+	paramDistance = generator.Param{Short: "r", Kind: generator.Float, Meta: "df", Default: "1.0",
+		Usage: "Distance factor for the size of the generated system"}
+	paramEcc = generator.Param{Short: "e", Kind: generator.Float, Meta: "ecc", Default: "0.0",
+		Usage: "Maximum eccentricity of the random orbits"}
+	paramInc = generator.Param{Short: "i", Kind: generator.Float, Meta: "inc", Default: "0.0",
+		Usage: "Maximum inclination in degrees of the random orbits"}
+	paramTest = generator.Param{Name: "test", Short: "t", Kind: generator.Int, Meta: "numTest", Default: "0",
+		Usage: "Number of extra massless test particles"}
+	paramClusterMass = generator.Param{Name: "cluster-mass", Kind: generator.Float, Meta: "kg", Default: "2e34",
+		Usage: "Total mass of the cluster"}
+	paramClusterRadius = generator.Param{Name: "cluster-radius", Kind: generator.Float, Meta: "m", Default: "3.086e16",
+		Usage: "Plummer scale radius or King core radius"}
+	paramVirial = generator.Param{Name: "virial", Kind: generator.Float, Meta: "q", Default: "0.5",
+		Usage: "Starting virial ratio T/|W| of the cluster, 0.5 for equilibrium"}
+	paramProjected = generator.Param{Name: "projected", Kind: generator.Bool,
+		Usage: "Flatten the cluster onto the plane of the screen for a 2D run"}
+)
+
+// Orbit spread from the -e and -i parameters
+func spreadOf(v generator.Values) (orbitSpread, error) {
+	ecc := v.Float("e")
+	if ecc < 0 || ecc >= 1 {
+		return orbitSpread{}, fmt.Errorf("invalid -e: eccentricity must be in [0, 1)")
+	}
+	return orbitSpread{ecc, v.Float("i") * math.Pi / 180}, nil
+}
+
+func clusterSpecOf(v generator.Values) clusterSpec {
+	return clusterSpec{
+		n:         v.Int("number"),
+		mass:      v.Float("cluster-mass"),
+		radius:    v.Float("cluster-radius"),
+		virial:    v.Float("virial"),
+		projected: v.Bool("projected"),
+	}
+}
+
+var builtinGenerators = []builtinGenerator{
+	{
+		spec: generator.Spec{
+			Name:        "random",
+			Description: "planets on random orbits around a heavy central body",
+			Params: []generator.Param{paramNumber,
+				{Short: "p", Kind: generator.Float, Meta: "pf", Default: "0.2",
+					Usage: "Perturbation factor of the velocities of the random planets"},
+				paramDistance, paramEcc, paramInc, paramTest},
+		},
+		build: func(ctx genContext) (*World, error) {
+			spread, err := spreadOf(ctx.values)
+			if err != nil {
+				return nil, err
+			}
+			v := ctx.values
+			world := randomWorld(ctx.width, ctx.height, v.Int("number"), v.Float("p"), v.Float("r"), spread, ctx.rng)
+			if n := v.Int("test"); n > 0 {
+				addTestParticles(world, n, v.Float("r"), spread, ctx.rng)
+			}
+			return world, nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "moons",
+			Description: "planets with moons around a heavy central body, -n bodies in total",
+			Params: []generator.Param{paramNumber,
+				{Name: "moons", Short: "m", Kind: generator.Int, Meta: "numMoons", Default: "3",
+					Usage: "Number of moons per planet"},
+				paramDistance, paramEcc, paramInc, paramTest,
+				{Name: "moon-e", Kind: generator.Float, Meta: "ecc", Default: "0.0",
+					Usage: "Maximum eccentricity of the moons' orbits around their planet"},
+				{Name: "moon-i", Kind: generator.Float, Meta: "deg", Default: "0.0",
+					Usage: "Maximum inclination of the moons' orbits"},
+				{Name: "hill", Kind: generator.Float, Meta: "f", Default: "0.5",
+					Usage: "Fraction of the planet's Hill radius the moons' orbits stay inside"}},
+		},
+		build: func(ctx genContext) (*World, error) {
+			spread, err := spreadOf(ctx.values)
+			if err != nil {
+				return nil, err
+			}
+			v := ctx.values
+			moons := moonSpec{orbitSpread{v.Float("moon-e"), v.Float("moon-i") * math.Pi / 180}, v.Float("hill")}
+			if moons.spread.ecc < 0 || moons.spread.ecc >= 1 {
+				return nil, fmt.Errorf("invalid --moon-e: eccentricity must be in [0, 1)")
+			}
+			if moons.hill <= 0 || moons.hill > 1 {
+				return nil, fmt.Errorf("invalid --hill: fraction must be in (0, 1]")
+			}
+			numBodies, numMoons := v.Int("number"), v.Int("moons")
+			totalBodies := numBodies
+			for (numBodies*numMoons + numBodies) > totalBodies {
+				numBodies -= 1
+			}
+			world := randomWithMoons(ctx.width, ctx.height, numBodies, numMoons, v.Float("r"), spread, moons, ctx.rng)
+			if n := v.Int("test"); n > 0 {
+				addTestParticles(world, n, v.Float("r"), spread, ctx.rng)
+			}
+			return world, nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "solar",
+			Description: "the solar system at J2000 from a table of orbital elements, or from JPL Horizons exports",
+			Params: []generator.Param{
+				{Name: "select", Kind: generator.String, Meta: "bodies", Default: "inner,moons",
+					Usage: "Comma separated body names or groups: all, planets, inner, outer, moons, pluto, asteroids"},
+				{Name: "horizons", Kind: generator.String, Meta: "files",
+					Usage: "Start from JPL Horizons vector table exports instead, comma separated files or directories"},
+			},
+		},
+		build: func(ctx genContext) (*World, error) {
+			if spec := ctx.values.String("horizons"); spec != "" {
+				world, vectors, err := horizonsSystem(spec, ctx.width, ctx.height, ctx.rng)
+				if err != nil {
+					return nil, err
+				}
+				world.horizons = vectors
+				return world, nil
+			}
+			world, err := solarSystem(ctx.width, ctx.height, ctx.values.String("select"), ctx.rng)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --select: %v", err)
+			}
+			return world, nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "galaxies",
+			Description: "two disk galaxies of -n test particles each on a parabolic encounter",
+			Params: []generator.Param{paramNumber,
+				{Name: "inclination", Kind: generator.Float, Meta: "deg", Default: "45",
+					Usage: "Inclination of the second disk to the encounter orbit"}},
+		},
+		build: func(ctx genContext) (*World, error) {
+			v := ctx.values
+			return galaxyCollision(ctx.width, ctx.height, v.Int("number"), v.Float("inclination")*math.Pi/180, ctx.rng), nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "plummer",
+			Description: "a star cluster sampled from a Plummer sphere",
+			Params:      []generator.Param{paramNumber, paramClusterMass, paramClusterRadius, paramVirial, paramProjected},
+		},
+		build: func(ctx genContext) (*World, error) {
+			return plummerCluster(ctx.width, ctx.height, clusterSpecOf(ctx.values), ctx.rng), nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "king",
+			Description: "a star cluster sampled from a King model",
+			Params: []generator.Param{paramNumber, paramClusterMass, paramClusterRadius, paramVirial, paramProjected,
+				{Name: "w0", Kind: generator.Float, Meta: "w0", Default: "6", Usage: "Central potential W0 of the King model"}},
+		},
+		build: func(ctx genContext) (*World, error) {
+			return kingCluster(ctx.width, ctx.height, clusterSpecOf(ctx.values), ctx.values.Float("w0"), ctx.rng), nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "periodic",
+			Description: "a known periodic solution of the equal mass three-body problem",
+			Params: []generator.Param{
+				{Name: "solution", Kind: generator.String, Meta: "name", Default: "figure-eight",
+					Usage: "Periodic three-body solution: figure-eight, lagrange, euler, broucke-a1, broucke-a2, henon-criss-cross"}},
+		},
+		build: func(ctx genContext) (*World, error) {
+			world, err := periodicWorld(ctx.width, ctx.height, ctx.values.String("solution"), 20000)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --solution: %v", err)
+			}
+			return world, nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "trojans",
+			Description: "-n test particles around the Lagrange points of the Sun and a planet, in a co-rotating view",
+			Params: []generator.Param{paramNumber,
+				{Name: "planet", Kind: generator.String, Meta: "name", Default: "jupiter",
+					Usage: "Planet of the Sun-planet pair"},
+				{Name: "points", Kind: generator.String, Meta: "list", Default: "L4,L5",
+					Usage: "Lagrange points to populate, comma separated from L1 to L5"},
+				{Name: "spread", Kind: generator.Float, Meta: "deg", Default: "5",
+					Usage: "Angular scatter of the particles around their Lagrange point"}},
+		},
+		build: func(ctx genContext) (*World, error) {
+			v := ctx.values
+			world, err := trojanSwarm(ctx.width, ctx.height, v.String("planet"), strings.Split(v.String("points"), ","),
+				v.Int("number"), v.Float("spread")*math.Pi/180, ctx.rng)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trojans options: %v", err)
+			}
+			return world, nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "disk",
+			Description: "a Sun with -n planetesimals in a protoplanetary disk from 0.5 to 0.5+2.5*df au",
+			Params: []generator.Param{paramNumber, paramDistance,
+				{Name: "surface", Kind: generator.Float, Meta: "p", Default: "1.5",
+					Usage: "Surface density of the disk falls as r^-p"},
+				{Name: "masses", Kind: generator.String, Meta: "spec", Default: "power,q=1.8,min=1e20,max=1e23",
+					Usage: "Planetesimal masses: power,q=<q>,min=<kg>,max=<kg> or lognormal,m=<kg>,sigma=<s>"},
+				{Name: "rayleigh", Kind: generator.Float, Meta: "sigma", Default: "0.01",
+					Usage: "Rayleigh scale of the eccentricities, inclinations in radians get half of it"},
+				{Name: "inflate", Kind: generator.Float, Meta: "f", Default: "1",
+					Usage: "Multiply planetesimal radii to speed up collisions"},
+				{Name: "gas", Kind: generator.String, Meta: "spec",
+					Usage: "Gas drag: epstein,eta=<eta>,tau=<s/m>, the stopping time is tau times the radius"}},
+		},
+		build: func(ctx genContext) (*World, error) {
+			v := ctx.values
+			df := v.Float("r")
+			spec := diskSpec{n: v.Int("number"), inner: 0.5 * au, outer: (0.5 + 2.5*df) * au,
+				surface: v.Float("surface"), rayleighE: v.Float("rayleigh"), inflate: v.Float("inflate")}
+			var err error
+			spec.masses, err = parseMassSpectrum(v.String("masses"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid --masses: %v", err)
+			}
+			world := protoplanetaryDisk(ctx.width, ctx.height, spec, ctx.rng)
+			if gasSpec := v.String("gas"); gasSpec != "" {
+				world.drag, err = parseGasDrag(gasSpec, world.bodies[0])
+				if err != nil {
+					return nil, fmt.Errorf("invalid --gas: %v", err)
+				}
+				fmt.Printf("GAS DRAG: %v\n", world.drag)
+			}
+			return world, nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "binary",
+			Description: "binary stars with circumbinary and single star planets, reporting the Holman-Wiegert limits",
+			Params: []generator.Param{
+				{Name: "mass-ratio", Kind: generator.Float, Meta: "q", Default: "0.5",
+					Usage: "Mass of star B relative to star A, one solar mass"},
+				{Name: "separation", Kind: generator.Float, Meta: "au", Default: "1",
+					Usage: "Semi-major axis of the binary orbit"},
+				{Name: "binary-e", Kind: generator.Float, Meta: "e", Default: "0.3",
+					Usage: "Eccentricity of the binary orbit"},
+				{Name: "planets", Kind: generator.String, Meta: "list", Default: "AB:2,AB:4,A:0.15,B:0.1",
+					Usage: "Planets as host:a with a in au, host AB for circumbinary orbits or A or B"}},
+		},
+		build: func(ctx genContext) (*World, error) {
+			v := ctx.values
+			spec := binarySpec{mass: solarBodies[0].mass, massRatio: v.Float("mass-ratio"),
+				separation: v.Float("separation") * au, ecc: v.Float("binary-e")}
+			var err error
+			spec.planets, err = parseBinaryPlanets(v.String("planets"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid binary options: %v", err)
+			}
+			world, err := binarySystem(ctx.width, ctx.height, spec, ctx.rng)
+			if err != nil {
+				return nil, fmt.Errorf("invalid binary options: %v", err)
+			}
+			return world, nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "belt",
+			Description: "the Sun and Jupiter with an asteroid belt of -n test particles between the --belt edges",
+			Params:      []generator.Param{paramNumber, paramEcc, paramInc},
+		},
+		build: func(ctx genContext) (*World, error) {
+			spread, err := spreadOf(ctx.values)
+			if err != nil {
+				return nil, err
+			}
+			return asteroidBelt(ctx.width, ctx.height, ctx.values.Int("number"), ctx.beltInner, ctx.beltOuter, spread,
+				ctx.rng), nil
+		},
+	},
+	{
+		spec: generator.Spec{
+			Name:        "file",
+			Description: "load the scenario FILE, see docs/scenario.md",
+		},
+		build: func(ctx genContext) (*World, error) {
+			if ctx.file == "" {
+				return nil, fmt.Errorf("file MODE needs a scenario FILE")
+			}
+			return loadScenarioWorld(ctx.file, ctx.width, ctx.height, ctx.rng)
+		},
+	},
+}
+
+func init() {
+	for _, g := range builtinGenerators {
+		generator.MustRegister(g)
+	}
+}
+
+// Build the world of the generator registered as mode. Generators from
+// other packages build a scenario that is loaded like a scenario file.
+func generateWorld(mode string, ctx genContext, raw map[string]string) (*World, error) {
+	g, ok := generator.Lookup(mode)
+	if !ok {
+		return nil, fmt.Errorf("MODE %v is not valid", mode)
+	}
+	var err error
+	ctx.values, err = g.Spec().Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v", err)
+	}
+	switch g := g.(type) {
+	case builtinGenerator:
+		return g.build(ctx)
+	case generator.ScenarioGenerator:
+		s, err := g.Generate(ctx.values, ctx.rng)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", mode, err)
+		}
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("%v: %v", mode, err)
+		}
+		fmt.Printf("Generated scenario %v\n", s.Name)
+		return worldFromScenario(s, ".", ctx.width, ctx.height, ctx.rng)
+	}
+	return nil, fmt.Errorf("generator %v cannot build a world", mode)
+}
+
+// Options section for the parameters of every registered generator, each
+// listed once with the MODEs that take it
+func generatorOptions() string {
+	var order []generator.Param
+	modes := map[string][]string{}
+	for _, g := range generator.All() {
+		spec := g.Spec()
+		for _, p := range spec.Params {
+			if _, ok := modes[p.Key()]; !ok {
+				order = append(order, p)
+			}
+			modes[p.Key()] = append(modes[p.Key()], spec.Name)
+		}
+	}
+	var b strings.Builder
+	b.WriteString("Generator options:\n")
+	for _, p := range order {
+		names := modes[p.Key()]
+		in := names[len(names)-1]
+		if len(names) > 1 {
+			in = strings.Join(names[:len(names)-1], ", ") + " and " + in
+		}
+		b.WriteString(generator.OptionLine(p, fmt.Sprintf("%v, in %v MODE", p.Usage, in)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Names of the registered generators
+func generatorNames() []string {
+	var names []string
+	for _, g := range generator.All() {
+		names = append(names, g.Spec().Name)
+	}
+	return names
+}
+
+// Help of one MODE, or the list of all of them
+func generatorHelp(mode string) (string, error) {
+	if mode == "" {
+		var b strings.Builder
+		b.WriteString("MODEs, see nbody-go help MODE for their options:\n")
+		for _, g := range generator.All() {
+			fmt.Fprintf(&b, "  %-10v  %v\n", g.Spec().Name, g.Spec().Description)
+		}
+		return b.String(), nil
+	}
+	g, ok := generator.Lookup(mode)
+	if !ok {
+		return "", fmt.Errorf("MODE %v is not valid, one of %v", mode, strings.Join(generatorNames(), ", "))
+	}
+	return generator.Help(g.Spec()), nil
+}
+
+// Raw values of the MODE's parameters from the command line
+func generatorValues(mode string, options docopt.Opts) map[string]string {
+	raw := map[string]string{}
+	g, ok := generator.Lookup(mode)
+	if !ok {
+		return raw
+	}
+	for _, p := range g.Spec().Params {
+		switch v := options[p.Option()].(type) {
+		case string:
+			raw[p.Key()] = v
+		case bool:
+			if v {
+				raw[p.Key()] = "true"
+			}
+		}
+	}
+	return raw
+}
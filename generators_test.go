@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"github.com/docopt/docopt-go"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"github.com/seifertd/nbody-go/generator"
+	"github.com/seifertd/nbody-go/scenario"
+	"math"
+	math_rand "math/rand"
+	"strings"
+	"testing"
+)
+
+// A generator from outside the viewer, n equal masses on a circle
+type ringGenerator struct{}
+
+func (ringGenerator) Spec() generator.Spec {
+	return generator.Spec{Name: "ring", Description: "equal masses on a circle", Params: []generator.Param{paramNumber,
+		{Name: "ring-radius", Kind: generator.Float, Meta: "m", Default: "1e11", Usage: "Radius of the ring"}}}
+}
+
+func (ringGenerator) Generate(v generator.Values, rng *math_rand.Rand) (*scenario.Scenario, error) {
+	s := &scenario.Scenario{Name: "ring", Timestep: 3600}
+	r := v.Float("ring-radius")
+	for i := 0; i < v.Int("number"); i++ {
+		angle := 2 * math.Pi * float64(i) / float64(v.Int("number"))
+		s.Bodies = append(s.Bodies, scenario.Body{Name: fmt.Sprintf("R%v", i),
+			Position: []float64{r * math.Cos(angle), r * math.Sin(angle)}, Velocity: []float64{0, 0}, Mass: 1e24, Radius: 1e6})
+	}
+	return s, nil
+}
+
+func TestGenerators(t *testing.T) {
+	resetSprites()
+	generator.MustRegister(ringGenerator{})
+	t.Cleanup(func() { generator.Unregister("ring") })
+	parse := func(args ...string) docopt.Opts {
+		parser := &docopt.Parser{HelpHandler: docopt.NoHelpHandler}
+		options, err := parser.ParseArgs(usage(), args, "")
+		if err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return options
+	}
+	if !strings.Contains(usage(), "--ring-radius=<m>") || !strings.Contains(usage(), "one of random, moons,") {
+		t.Errorf("usage lacks the registered generators:\n%v", usage())
+	}
+
+	ctx := genContext{width: 1024, height: 1024, rng: newRand(1)}
+	world, err := generateWorld("ring", ctx, generatorValues("ring", parse("-n", "7", "--ring-radius=2e11", "ring")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(world.bodies) != 7 || math.Abs(world.bodies[0].Pos.X-2e11) > 1 || world.timestep() != 3600 {
+		t.Errorf("ring world not built from its scenario: %v bodies", len(world.bodies))
+	}
+
+	world, err = generateWorld("king", ctx, generatorValues("king", parse("-n", "25", "--projected", "king")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range world.bodies {
+		if b.Pos.Z != 0 {
+			t.Fatalf("projected cluster has a body off the plane: %v", b)
+		}
+	}
+	if len(world.bodies) != 25 {
+		t.Errorf("king cluster has %v bodies, expected 25", len(world.bodies))
+	}
+
+	if _, err := generateWorld("disk", ctx, generatorValues("disk", parse("--surface=steep", "disk"))); err == nil {
+		t.Errorf("a non numeric --surface should be an error")
+	}
+	if _, err := generateWorld("random", ctx, generatorValues("random", parse("-e", "1.5", "random"))); err == nil {
+		t.Errorf("an eccentricity over 1 should be an error")
+	}
+	if _, err := generateWorld("file", ctx, generatorValues("file", parse("file"))); err == nil {
+		t.Errorf("file MODE without a FILE should be an error")
+	}
+	if help, err := generatorHelp("ring"); err != nil || !strings.Contains(help, "Radius of the ring [default: 1e11]") {
+		t.Errorf("wrong help for ring: %v %v", help, err)
+	}
+	if _, err := generatorHelp("square"); err == nil {
+		t.Errorf("help for an unknown MODE should be an error")
+	}
+}
+
+func TestMoonsStayInsideHillSpheres(t *testing.T) {
+	resetSprites()
+	moons := moonSpec{orbitSpread{0.2, 0.3}, 0.5}
+	world := randomWithMoons(1024, 1024, 10, 3, 1.0, orbitSpread{0.1, 0.1}, moons, newRand(7))
+	center := world.bodies[0]
+	stable := 0
+	phases := map[int]bool{}
+	for i := 1; i < len(world.bodies); i += 4 {
+		planet := world.bodies[i]
+		el := body.ElementsOf(vector.Sub(planet.Pos, center.Pos), vector.Sub(planet.Vel, center.Vel),
+			G*(center.Mass+planet.Mass))
+		hill := moons.hill * el.HillRadius(planet.Mass, center.Mass)
+		for _, moon := range world.bodies[i+1 : i+4] {
+			rel := vector.Sub(moon.Pos, planet.Pos)
+			mel := body.ElementsOf(rel, vector.Sub(moon.Vel, planet.Vel), G*(planet.Mass+moon.Mass))
+			if mel.E >= 1 || mel.E > 0.2+1e-9 || mel.I > 0.3+1e-9 {
+				t.Errorf("%v has e %v and i %v outside the moon spread", moon.Name, mel.E, mel.I)
+			}
+			if mel.A*(1-mel.E) < planet.Radius {
+				t.Errorf("%v dips into its planet", moon.Name)
+			}
+			if mel.A*(1+mel.E) <= hill*(1+1e-9) {
+				stable++
+			} else if (planet.Radius+2*moon.Radius)/(1-mel.E)*(1+mel.E) <= hill {
+				t.Errorf("%v could have been placed inside the Hill sphere", moon.Name)
+			}
+			phases[int(math.Atan2(rel.Y, rel.X)*10)] = true
+		}
+	}
+	if stable < 20 {
+		t.Errorf("only %v of 30 moons inside their Hill spheres", stable)
+	}
+	if len(phases) < 20 {
+		t.Errorf("moons should start at random phases: %v distinct", len(phases))
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/horizons"
+	math_rand "math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Expand a comma separated list of Horizons exports. Directories stand for
+// every .txt file in them.
+func horizonsPaths(spec string) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			matches, _ := filepath.Glob(filepath.Join(p, "*.txt"))
+			sort.Strings(matches)
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%v: no .txt Horizons exports found", p)
+			}
+			paths = append(paths, matches...)
+		} else {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// Solar system at the epoch of a set of Horizons vector table exports
+func horizonsSystem(spec string, w, h int, rng *math_rand.Rand) (*World, []*horizons.Vectors, error) {
+	paths, err := horizonsPaths(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	var all []*horizons.Vectors
+	for _, path := range paths {
+		v, err := horizons.Load(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		all = append(all, v)
+	}
+	bodies, epoch, err := horizons.Bodies(all)
+	if err != nil {
+		return nil, nil, err
+	}
+	world := &World{
+		scale:   1.0,
+		spt:     600,
+		running: true,
+		elapsed: 0,
+		bodies:  bodies,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+		epoch:   epoch,
+	}
+	world.fitToScreen()
+	fmt.Printf("Horizons epoch JD %v (%v)\n", epoch, horizons.Time(epoch).Format("2006-01-02 15:04 TDB"))
+	for _, b := range world.bodies {
+		if sprite, ok := solarSprite(b.Name); ok {
+			b.Sprite = sprite
+		} else {
+			b.Sprite = randomPlanetSprite(rng)
+		}
+		fmt.Printf("%v\n", b)
+	}
+	return world, all, nil
+}
+
+// Print how far each body is from where Horizons has it at the nearest
+// date in the exports. Horizons vectors are relative to their center body,
+// or to the barycenter when the center is not a body in the world.
+func compareHorizons(world *World, all []*horizons.Vectors) {
+	jd := world.epoch + world.elapsed/86400
+	fmt.Printf("Comparison with Horizons at %v:\n", horizons.Time(jd).Format("2006-01-02 15:04 TDB"))
+	origin, originVel := world.barycenter()
+	for _, b := range world.bodies {
+		if b.Name == all[0].Center {
+			origin, originVel = b.Pos, b.Vel
+		}
+	}
+	for _, v := range all {
+		r := v.At(jd)
+		for _, b := range world.bodies {
+			if b.Name != v.Target {
+				continue
+			}
+			pos := vector.Sub(b.Pos, origin)
+			vel := vector.Sub(b.Vel, originVel)
+			fmt.Printf("  %v: %.2fd from export, position error %.4g km, velocity error %.4g m/s\n",
+				b.Name, r.JD-jd, pos.DistanceTo(r.Pos)/1e3, vel.DistanceTo(r.Vel))
+		}
+	}
+}
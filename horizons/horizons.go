@@ -0,0 +1,285 @@
+// Package horizons reads state vectors from JPL Horizons vector table text
+// exports, saved locally, so runs can start from the real configuration of
+// the solar system at a given date.
+package horizons
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	km = 1e3
+	au = 1.495978707e11
+	// Julian date of the unix epoch
+	unixEpochJD = 2440587.5
+)
+
+// Mass in kg and radius in m of the major bodies by Horizons id, used when
+// the file header does not give them.
+var knownBodies = map[int][2]float64{
+	10:  {1.98847e30, 695_700e3},
+	199: {3.3011e23, 2_439.7e3},
+	299: {4.8675e24, 6_051.8e3},
+	399: {5.9722e24, 6_371.0e3},
+	301: {7.346e22, 1_737.4e3},
+	499: {6.4171e23, 3_389.5e3},
+	599: {1.89813e27, 69_911e3},
+	699: {5.6834e26, 58_232e3},
+	799: {8.6810e25, 25_362e3},
+	899: {1.02413e26, 24_622e3},
+	999: {1.303e22, 1_188.3e3},
+}
+
+// Record is one row of the vector table in SI units
+type Record struct {
+	JD  float64
+	Pos vector.Vector
+	Vel vector.Vector
+}
+
+// Vectors is the contents of one Horizons vector table export
+type Vectors struct {
+	Target   string
+	TargetId int
+	Center   string
+	CenterId int
+	// Mass in kg and radius in m, 0 when unknown
+	Mass    float64
+	Radius  float64
+	Records []Record
+}
+
+var (
+	targetRe = regexp.MustCompile(`Target body name:\s*(.*?)\s*\((-?\d+)\)`)
+	// Small bodies have designations rather than numeric ids
+	targetNameRe = regexp.MustCompile(`Target body name:\s*(.*?)\s*(\{|$)`)
+	centerRe     = regexp.MustCompile(`Center body name:\s*(.*?)\s*\((-?\d+)\)`)
+	unitsRe      = regexp.MustCompile(`Output units\s*:\s*([A-Z]+)-([A-Z]+)`)
+	gmRe         = regexp.MustCompile(`GM,?\s*\(?km\^3/s\^2\)?\s*=\s*~?([0-9.Ee+-]+?)(\+-|\s|$)`)
+	radiusRe     = regexp.MustCompile(`(?i)mean radius[^=]*=\s*~?([0-9.]+)`)
+	valueRe      = regexp.MustCompile(`\b(VX|VY|VZ|X|Y|Z)\s*=\s*([-+0-9.Ee]+)`)
+)
+
+// Read a vector table export. Both the default text layout and the CSV
+// layout of the table are understood.
+func Read(r io.Reader) (*Vectors, error) {
+	v := &Vectors{}
+	lengthUnit, timeUnit := km, 1.0
+	inTable := false
+	var current *Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "$$SOE" {
+			inTable = true
+			continue
+		}
+		if trimmed == "$$EOE" {
+			inTable = false
+			continue
+		}
+		if !inTable {
+			if m := targetRe.FindStringSubmatch(line); m != nil {
+				v.Target = m[1]
+				v.TargetId, _ = strconv.Atoi(m[2])
+			} else if m := targetNameRe.FindStringSubmatch(line); m != nil {
+				v.Target = m[1]
+			}
+			if m := centerRe.FindStringSubmatch(line); m != nil {
+				v.Center = m[1]
+				v.CenterId, _ = strconv.Atoi(m[2])
+			}
+			if m := unitsRe.FindStringSubmatch(line); m != nil {
+				switch m[1] {
+				case "KM":
+					lengthUnit = km
+				case "AU":
+					lengthUnit = au
+				default:
+					return nil, fmt.Errorf("line %d: unsupported length unit %v", lineNo, m[1])
+				}
+				switch m[2] {
+				case "S":
+					timeUnit = 1
+				case "D":
+					timeUnit = 86400
+				default:
+					return nil, fmt.Errorf("line %d: unsupported time unit %v", lineNo, m[2])
+				}
+			}
+			if m := gmRe.FindStringSubmatch(line); m != nil && v.Mass == 0 {
+				if gm, err := strconv.ParseFloat(m[1], 64); err == nil {
+					v.Mass = gm * 1e9 / body.G
+				}
+			}
+			if m := radiusRe.FindStringSubmatch(line); m != nil && v.Radius == 0 {
+				if r, err := strconv.ParseFloat(m[1], 64); err == nil {
+					v.Radius = r * km
+				}
+			}
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+		// CSV layout: JD, calendar date, X, Y, Z, VX, VY, VZ, ...
+		if strings.Contains(trimmed, ",") {
+			fields := strings.Split(trimmed, ",")
+			if len(fields) < 8 {
+				return nil, fmt.Errorf("line %d: expected at least 8 columns, got %d", lineNo, len(fields))
+			}
+			var values [7]float64
+			for i, f := range append(fields[:1:1], fields[2:8]...) {
+				x, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", lineNo, err)
+				}
+				values[i] = x
+			}
+			v.Records = append(v.Records, Record{
+				JD:  values[0],
+				Pos: vector.MultScalar(vector.Vector{values[1], values[2], values[3]}, lengthUnit),
+				Vel: vector.MultScalar(vector.Vector{values[4], values[5], values[6]}, lengthUnit/timeUnit),
+			})
+			continue
+		}
+		// Text layout: a JD line followed by X/Y/Z and VX/VY/VZ lines
+		if fields := strings.Fields(trimmed); len(fields) > 1 && fields[1] == "=" {
+			jd, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad julian date: %v", lineNo, err)
+			}
+			v.Records = append(v.Records, Record{JD: jd})
+			current = &v.Records[len(v.Records)-1]
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: values before the first date", lineNo)
+		}
+		for _, m := range valueRe.FindAllStringSubmatch(trimmed, -1) {
+			x, err := strconv.ParseFloat(m[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad %v: %v", lineNo, m[1], err)
+			}
+			switch m[1] {
+			case "X":
+				current.Pos.X = x * lengthUnit
+			case "Y":
+				current.Pos.Y = x * lengthUnit
+			case "Z":
+				current.Pos.Z = x * lengthUnit
+			case "VX":
+				current.Vel.X = x * lengthUnit / timeUnit
+			case "VY":
+				current.Vel.Y = x * lengthUnit / timeUnit
+			case "VZ":
+				current.Vel.Z = x * lengthUnit / timeUnit
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if v.Target == "" {
+		return nil, fmt.Errorf("no \"Target body name\" found, is this a Horizons vector table?")
+	}
+	if len(v.Records) == 0 {
+		return nil, fmt.Errorf("%v: no vectors between $$SOE and $$EOE", v.Target)
+	}
+	if known, ok := knownBodies[v.TargetId]; ok {
+		if v.Mass == 0 {
+			v.Mass = known[0]
+		}
+		if v.Radius == 0 {
+			v.Radius = known[1]
+		}
+	}
+	return v, nil
+}
+
+func Load(path string) (*Vectors, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	v, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return v, nil
+}
+
+// Body at the first epoch in the file
+func (v *Vectors) Body() (*body.Body, error) {
+	if v.Mass == 0 {
+		return nil, fmt.Errorf("%v: mass unknown, the header has no GM", v.Target)
+	}
+	if v.Radius == 0 {
+		return nil, fmt.Errorf("%v: radius unknown, the header has no mean radius", v.Target)
+	}
+	r := v.Records[0]
+	return body.NewBodyVector(v.Target, r.Pos, r.Vel, v.Radius, v.Mass, nil), nil
+}
+
+// Record nearest to the julian date jd
+func (v *Vectors) At(jd float64) Record {
+	best := v.Records[0]
+	for _, r := range v.Records[1:] {
+		if math.Abs(r.JD-jd) < math.Abs(best.JD-jd) {
+			best = r
+		}
+	}
+	return best
+}
+
+// Build bodies from several exports, which must share an epoch and a
+// center. When the center is a body, e.g. the Sun, and no export is for
+// it, it is added at the origin at rest. Returns the bodies and the epoch.
+func Bodies(all []*Vectors) ([]*body.Body, float64, error) {
+	if len(all) == 0 {
+		return nil, 0, fmt.Errorf("no Horizons exports given")
+	}
+	epoch := all[0].Records[0].JD
+	center := all[0].CenterId
+	var bodies []*body.Body
+	haveCenter := false
+	for _, v := range all {
+		if v.Records[0].JD != epoch {
+			return nil, 0, fmt.Errorf("%v starts at JD %v but %v starts at JD %v",
+				v.Target, v.Records[0].JD, all[0].Target, epoch)
+		}
+		if v.CenterId != center {
+			return nil, 0, fmt.Errorf("%v is relative to %v but %v is relative to %v",
+				v.Target, v.Center, all[0].Target, all[0].Center)
+		}
+		haveCenter = haveCenter || v.TargetId == center
+		b, err := v.Body()
+		if err != nil {
+			return nil, 0, err
+		}
+		bodies = append(bodies, b)
+	}
+	if known, ok := knownBodies[center]; ok && !haveCenter {
+		sun := body.NewBody(all[0].Center, 0, 0, known[1], known[0], 0, 0, nil)
+		bodies = append([]*body.Body{sun}, bodies...)
+	}
+	return bodies, epoch, nil
+}
+
+// Calendar time of a julian date
+func Time(jd float64) time.Time {
+	return time.Unix(0, 0).UTC().Add(time.Duration((jd - unixEpochJD) * 86400 * float64(time.Second)))
+}
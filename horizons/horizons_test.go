@@ -0,0 +1,76 @@
+package horizons
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-12*math.Abs(b)
+}
+
+func TestReadTextLayout(t *testing.T) {
+	v, err := Load("testdata/earth.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Target != "Earth" || v.TargetId != 399 || v.Center != "Sun" || v.CenterId != 10 {
+		t.Errorf("target or center not read: %+v", v)
+	}
+	if len(v.Records) != 3 || v.Records[1].JD != 2451546 {
+		t.Fatalf("expected 3 records: %+v", v.Records)
+	}
+	r := v.Records[0]
+	if !near(r.Pos.X, -2.649903375682292e10) || !near(r.Vel.Y, -5.018052308799582e3) || !near(r.Pos.Z, -6.112214304122701e5) {
+		t.Errorf("vectors not converted from km and km/s: %+v", r)
+	}
+	// Mass comes from GM in the header
+	if math.Abs(v.Mass-5.9722e24)/5.9722e24 > 1e-3 || v.Radius != 6371.01e3 {
+		t.Errorf("physical data not read from header: m=%v r=%v", v.Mass, v.Radius)
+	}
+	if at := v.At(2451546.9); at.JD != 2451547 {
+		t.Errorf("nearest record should be the last one: %v", at.JD)
+	}
+}
+
+func TestReadCSVLayout(t *testing.T) {
+	v, err := Load("testdata/mars-csv.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := v.Records[0]
+	if !near(r.Pos.X, 1.390715921746036*au) || !near(r.Vel.Y, 1.518620307613718e-02*au/86400) {
+		t.Errorf("vectors not converted from au and au/day: %+v", r)
+	}
+	// No physical data in the header, so the known values are used
+	if v.Mass != 6.4171e23 || v.Radius != 3389.5e3 {
+		t.Errorf("known mass and radius not used: m=%v r=%v", v.Mass, v.Radius)
+	}
+}
+
+func TestBodies(t *testing.T) {
+	earth, _ := Load("testdata/earth.txt")
+	mars, _ := Load("testdata/mars-csv.txt")
+	bodies, epoch, err := Bodies([]*Vectors{earth, mars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epoch != 2451545 || len(bodies) != 3 || bodies[0].Name != "Sun" || bodies[2].Name != "Mars" {
+		t.Errorf("expected the Sun to be added at the center: %v %v", epoch, bodies)
+	}
+	if Time(epoch).Format("2006-01-02 15:04") != "2000-01-01 12:00" {
+		t.Errorf("epoch should be J2000: %v", Time(epoch))
+	}
+
+	mars.Records[0].JD += 1
+	if _, _, err := Bodies([]*Vectors{earth, mars}); err == nil || !strings.Contains(err.Error(), "JD") {
+		t.Errorf("exports with different epochs should be an error: %v", err)
+	}
+}
+
+func TestNotAnExport(t *testing.T) {
+	if _, err := Read(strings.NewReader("hello\nworld\n")); err == nil {
+		t.Errorf("text without a target should be an error")
+	}
+}
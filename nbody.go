@@ -11,6 +11,9 @@ import (
 	"github.com/faiface/pixel/text"
 	"github.com/seifertd/go/vector"
 	"github.com/seifertd/nbody-go/body"
+	"github.com/seifertd/nbody-go/generator"
+	"github.com/seifertd/nbody-go/horizons"
+	"github.com/seifertd/nbody-go/trajectory"
 	"golang.org/x/image/colornames"
 	"golang.org/x/image/font/basicfont"
 	"image"
@@ -26,15 +29,57 @@ import (
 const (
 	G         = 6.674e-11
 	MinRadius = 4.0
+	// How often the info display recomputes the O(N²) energy and angular
+	// momentum
+	InfoRefresh = 500 * time.Millisecond
 )
 
-func initRand() {
+// Pick a seed from the secure random number generator when none is given
+func randomSeed() int64 {
 	var b [8]byte
 	_, err := crypto_rand.Read(b[:])
 	if err != nil {
 		panic("Unable to seed math/rand package with secure random number generator")
 	}
-	math_rand.Seed(int64(binary.LittleEndian.Uint64(b[:])))
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// Random source that counts its draws, so its state can be checkpointed and
+// restored by replaying them from the seed
+type countingSource struct {
+	src   math_rand.Source64
+	seed  int64
+	draws uint64
+}
+
+func newSource(seed int64) *countingSource {
+	return &countingSource{src: math_rand.NewSource(seed).(math_rand.Source64), seed: seed}
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed, s.draws = seed, 0
+}
+
+// Advance the source until it has made draws draws since it was seeded
+func (s *countingSource) skipTo(draws uint64) {
+	for s.draws < draws {
+		s.Int63()
+	}
+}
+
+func newRand(seed int64) *math_rand.Rand {
+	return math_rand.New(newSource(seed))
 }
 
 func loadPicture(path string) (pixel.Picture, error) {
@@ -74,15 +119,15 @@ func loadSprite(name, path string) *pixel.Sprite {
 	}
 }
 
-func randomPlanetSprite() *pixel.Sprite {
+func randomPlanetSprite(rng *math_rand.Rand) *pixel.Sprite {
 	if circleMode {
 		return sprites["circle"]
 	}
-	i := math_rand.Intn(numPlanetSprites + 1) // include an extra for Earth
+	i := rng.Intn(numPlanetSprites + 1) // include an extra for Earth
 	if i == numPlanetSprites {
 		return sprites["earth"]
 	} else {
-		name := fmt.Sprintf("planet%v", math_rand.Intn(numPlanetSprites))
+		name := fmt.Sprintf("planet%v", rng.Intn(numPlanetSprites))
 		return sprites[name]
 	}
 }
@@ -92,43 +137,265 @@ type World struct {
 	mpp     float64
 	spt     int
 	running bool
-	elapsed int
+	elapsed float64
 	bodies  []*body.Body
 	width   int
 	height  int
 	mag     float64
+	// static background fields added on top of body-body gravity
+	potentials []body.Potential
+	// interaction between bodies, Newtonian gravity when nil
+	law body.ForceLaw
+	// drag of a gas disk, none when nil
+	drag *body.GasDrag
+	// semi-major axes of the test particles over time, none when nil
+	histogram *smaHistogram
+	// star passing through, nil without one
+	flyby *flyby
+	// trajectory file being written, nil when not recording
+	recorder *recorder
+	// JSON Lines event log, nil when not logging
+	events *eventLog
+	// JPL Horizons vectors the world started from, compared against on exit
+	horizons []*horizons.Vectors
+	// seed the world was generated from
+	seed int64
+	// random source the world was generated from, nil when not kept
+	source *countingSource
+	// every collision so far, for accretion histories
+	mergers body.MergerTree
+	// seconds of world time per integration step, 1 when zero
+	dt float64
+	// keep the camera on the barycenter
+	followBarycenter bool
+	// julian date at the start of the run, 0 when not a real date
+	epoch float64
+	// draw in the frame rotating with the two heaviest bodies
+	corotate bool
+	// rotation of the view about frameCenter, set by updateFrame
+	frameCenter vector.Vector
+	frameAngle  float64
 }
 
 func (w World) worldToScreen(coords *vector.Vector) vector.Vector {
-	return vector.Vector{coords.X / w.mpp * w.scale * w.mag, coords.Y / w.mpp * w.scale * w.mag, 0}
+	x, y := coords.X, coords.Y
+	if w.frameAngle != 0 {
+		c, s := math.Cos(w.frameAngle), math.Sin(w.frameAngle)
+		dx, dy := x-w.frameCenter.X, y-w.frameCenter.Y
+		x, y = w.frameCenter.X+c*dx+s*dy, w.frameCenter.Y-s*dx+c*dy
+	}
+	return vector.Vector{x / w.mpp * w.scale * w.mag, y / w.mpp * w.scale * w.mag, 0}
+}
+
+// The heaviest and second heaviest bodies, nil when there are too few
+func (w World) heaviestPair() (*body.Body, *body.Body) {
+	var primary, secondary *body.Body
+	for _, b := range w.bodies {
+		if primary == nil || b.Mass > primary.Mass {
+			primary, secondary = b, primary
+		} else if secondary == nil || b.Mass > secondary.Mass {
+			secondary = b
+		}
+	}
+	return primary, secondary
+}
+
+// Set up the co-rotating view for this frame: rotate about the barycenter
+// of the two heaviest bodies so the line between them keeps along the x
+// axis, the heavier one on the left.
+func (w *World) updateFrame() {
+	w.frameAngle = 0
+	if !w.corotate {
+		return
+	}
+	primary, secondary := w.heaviestPair()
+	if secondary == nil || secondary.Mass == 0 {
+		return
+	}
+	d := vector.Sub(secondary.Pos, primary.Pos)
+	w.frameAngle = math.Atan2(d.Y, d.X)
+	m := primary.Mass + secondary.Mass
+	w.frameCenter = vector.DivScalar(vector.Add(vector.MultScalar(primary.Pos, primary.Mass),
+		vector.MultScalar(secondary.Pos, secondary.Mass)), m)
 }
 
 func (w World) worldTime() string {
-	d := w.elapsed / (3600 * 24)
-	h := (w.elapsed % (3600 * 24)) / 3600
-	m := (w.elapsed % 3600) / 60
-	s := w.elapsed % 60
+	elapsed := int64(w.elapsed)
+	d := elapsed / (3600 * 24)
+	h := (elapsed % (3600 * 24)) / 3600
+	m := (elapsed % 3600) / 60
+	s := elapsed % 60
 	return fmt.Sprintf("%dd %02dh%02dm%02ds", d, h, m, s)
 }
 
-func (w World) escaped(body *body.Body) bool {
-	sun := w.bodies[0]
-	radius := body.Pos.DistanceTo(sun.Pos)
+// Set meters per pixel so every body fits on the screen
+func (w *World) fitToScreen() {
+	extent := 0.0
+	for _, b := range w.bodies {
+		extent = math.Max(extent, b.Pos.Magnitude())
+	}
+	w.mpp = math.Max(extent, 1) * 2.4 * w.scale / float64(min(w.width, w.height))
+}
+
+// Mass weighted sums over the massive bodies, for the center of mass of
+// everything but one body
+type massMoments struct {
+	pos  vector.Vector
+	mom  vector.Vector
+	mass float64
+}
+
+func (w World) massMoments() massMoments {
+	var m massMoments
+	for _, b := range w.bodies {
+		if !b.TestParticle {
+			m.pos.Add(vector.MultScalar(b.Pos, b.Mass))
+			m.mom.Add(vector.MultScalar(b.Vel, b.Mass))
+			m.mass += b.Mass
+		}
+	}
+	return m
+}
+
+// A body escapes when it is far off screen and faster than the escape
+// velocity from the center of mass of all other massive bodies, so worlds
+// with several suns or none at bodies[0] work too.
+func (w World) escaped(body *body.Body, moments massMoments) bool {
+	pos, mom, mass := moments.pos, moments.mom, moments.mass
+	if !body.TestParticle {
+		pos = vector.Sub(pos, vector.MultScalar(body.Pos, body.Mass))
+		mom = vector.Sub(mom, vector.MultScalar(body.Vel, body.Mass))
+		mass -= body.Mass
+	}
+	if mass <= 0 {
+		return false
+	}
+	radius := body.Pos.DistanceTo(vector.DivScalar(pos, mass))
+	speed := vector.Sub(body.Vel, vector.DivScalar(mom, mass)).Magnitude()
 	maxDistance := math.Sqrt(float64(iPow(w.width, 2)+iPow(w.height, 2))) * 10.0 * w.mag * w.mpp
 
-	return radius > maxDistance && body.Vel.Magnitude() > math.Sqrt(2.0*G*sun.Mass/radius)
+	return radius > maxDistance && speed > math.Sqrt(2.0*G*mass/radius)
+}
+
+func (w World) timestep() float64 {
+	if w.dt == 0 {
+		return 1
+	}
+	return w.dt
+}
+
+func (w World) forceLaw() body.ForceLaw {
+	if w.law == nil {
+		return body.Newtonian{}
+	}
+	return w.law
+}
+
+// Total energy of the world: kinetic, pairwise interaction under the force
+// law and the energy of each body in the external potentials.
+func (w World) energy() float64 {
+	return w.kineticEnergy() + w.potentialEnergy()
+}
+
+func (w World) kineticEnergy() float64 {
+	e := 0.0
+	for _, b := range w.bodies {
+		e += 0.5 * b.Mass * b.Vel.Dot(b.Vel)
+	}
+	return e
+}
+
+func (w World) potentialEnergy() float64 {
+	e := 0.0
+	law := w.forceLaw()
+	massive := w.massiveBodies()
+	for i, b := range massive {
+		for _, b2 := range massive[i+1:] {
+			// Average the two sides for laws that are not symmetric
+			e += 0.5 * (law.Energy(b, b2) + law.Energy(b2, b))
+		}
+		for _, p := range w.potentials {
+			e += b.Mass * p.Energy(b.Pos)
+		}
+	}
+	return e
+}
+
+// Bodies that exert gravity, i.e. everything but the test particles
+func (w *World) massiveBodies() []*body.Body {
+	massive := make([]*body.Body, 0, len(w.bodies))
+	for _, b := range w.bodies {
+		if !b.TestParticle {
+			massive = append(massive, b)
+		}
+	}
+	return massive
 }
 
-func (w *World) calculateAcceleration(body *body.Body, c chan vector.Vector) {
+// Center of mass position and velocity of the world
+func (w World) barycenter() (vector.Vector, vector.Vector) {
+	pos := vector.Vector{0, 0, 0}
+	vel := vector.Vector{0, 0, 0}
+	m := 0.0
+	for _, b := range w.bodies {
+		pos.Add(vector.MultScalar(b.Pos, b.Mass))
+		vel.Add(vector.MultScalar(b.Vel, b.Mass))
+		m += b.Mass
+	}
+	if m == 0 {
+		return pos, vel
+	}
+	return vector.DivScalar(pos, m), vector.DivScalar(vel, m)
+}
+
+// Shift the world into the barycentric frame: center of mass at the origin
+// with zero total momentum, so the system does not drift over long runs.
+func (w *World) toBarycentricFrame() {
+	pos, vel := w.barycenter()
+	for _, b := range w.bodies {
+		b.Pos.Sub(pos)
+		b.Vel.Sub(vel)
+	}
+}
+
+// Add the potentials from the options to a generated world, put it in its
+// frame and then send the flyby star through it, which is placed relative
+// to the barycenter
+func setUpWorld(w *World, potentials []body.Potential, rawFrame bool, flybyParams map[string]float64) error {
+	w.potentials = append(w.potentials, potentials...)
+	// External potentials are fixed in space and define the frame themselves
+	if !rawFrame && len(w.potentials) == 0 {
+		w.toBarycentricFrame()
+	}
+	if flybyParams != nil {
+		return startFlyby(w, flybyParams)
+	}
+	return nil
+}
+
+// Total angular momentum about the origin, orbital plus spin
+func (w World) angularMomentum() vector.Vector {
+	l := vector.Vector{0, 0, 0}
+	for _, b := range w.bodies {
+		l.Add(vector.MultScalar(body.Cross(b.Pos, b.Vel), b.Mass))
+		l.Add(b.Spin)
+	}
+	return l
+}
+
+func (w *World) calculateAcceleration(body *body.Body, massive []*body.Body, law body.ForceLaw, c chan vector.Vector) {
 	deltaA := vector.Vector{0, 0, 0}
-	for _, body2 := range w.bodies {
+	for _, body2 := range massive {
 		if body == body2 {
 			continue
 		}
-		d := math.Sqrt(math.Pow(body.Pos.X-body2.Pos.X, 2) + math.Pow(body.Pos.Y-body2.Pos.Y, 2))
-		acc := vector.New2DVector((body2.Pos.X-body.Pos.X)/d, (body2.Pos.Y-body.Pos.Y)/d)
-		acc.MultScalar(G * body2.Mass / (d * d))
-		deltaA.Add(acc)
+		deltaA.Add(law.Acceleration(body, body2))
+	}
+	for _, p := range w.potentials {
+		deltaA.Add(p.Acceleration(body.Pos))
+	}
+	if w.drag != nil {
+		deltaA.Add(w.drag.Acceleration(body))
 	}
 	c <- deltaA
 }
@@ -145,6 +412,9 @@ func (w *World) removeBody(toRemove *body.Body) {
 		w.bodies[i] = nil
 	}
 	w.bodies = newBodies
+	if w.events != nil {
+		w.events.forget(toRemove.Id)
+	}
 }
 
 func (w *World) tick() {
@@ -152,8 +422,9 @@ func (w *World) tick() {
 		return
 	}
 
+	dt := w.timestep()
 	for i := 0; i < w.spt; i++ {
-		w.elapsed += 1
+		w.elapsed += dt
 
 		// Store initial positions and velocities
 		initialPos := make([]vector.Vector, len(w.bodies))
@@ -182,7 +453,7 @@ func (w *World) tick() {
 		}
 
 		// Step 2: Calculate k2 (derivatives at the midpoint using k1)
-		w.applyHalfStep(initialPos, initialVel, k1Vel, k1Acc)
+		w.applyHalfStep(initialPos, initialVel, k1Vel, k1Acc, dt)
 		w.calculateAllAccelerations(k2Acc)
 		for i, body := range w.bodies {
 			k2Vel[i] = vector.Vector{body.Vel.X, body.Vel.Y, body.Vel.Z}
@@ -190,7 +461,7 @@ func (w *World) tick() {
 
 		// Step 3: Calculate k3 (derivatives at the midpoint using k2)
 		w.resetToInitial(initialPos, initialVel)
-		w.applyHalfStep(initialPos, initialVel, k2Vel, k2Acc)
+		w.applyHalfStep(initialPos, initialVel, k2Vel, k2Acc, dt)
 		w.calculateAllAccelerations(k3Acc)
 		for i, body := range w.bodies {
 			k3Vel[i] = vector.Vector{body.Vel.X, body.Vel.Y, body.Vel.Z}
@@ -198,7 +469,7 @@ func (w *World) tick() {
 
 		// Step 4: Calculate k4 (derivatives at the end using k3)
 		w.resetToInitial(initialPos, initialVel)
-		w.applyFullStep(initialPos, initialVel, k3Vel, k3Acc)
+		w.applyFullStep(initialPos, initialVel, k3Vel, k3Acc, dt)
 		w.calculateAllAccelerations(k4Acc)
 		for i, body := range w.bodies {
 			k4Vel[i] = vector.Vector{body.Vel.X, body.Vel.Y, body.Vel.Z}
@@ -208,7 +479,7 @@ func (w *World) tick() {
 		w.resetToInitial(initialPos, initialVel)
 
 		for i, body := range w.bodies {
-			// Update velocity: v(t+dt) = v(t) + (1/6) * (k1 + 2*k2 + 2*k3 + k4)
+			// Update velocity: v(t+dt) = v(t) + (dt/6) * (k1 + 2*k2 + 2*k3 + k4)
 			velChange := vector.Vector{0, 0, 0}
 			velChange.Add(k1Acc[i])
 
@@ -221,11 +492,11 @@ func (w *World) tick() {
 			velChange.Add(temp)
 
 			velChange.Add(k4Acc[i])
-			velChange.MultScalar(1.0 / 6.0)
+			velChange.MultScalar(dt / 6.0)
 
 			body.Vel.Add(velChange)
 
-			// Update position: x(t+dt) = x(t) + (1/6) * (k1 + 2*k2 + 2*k3 + k4)
+			// Update position: x(t+dt) = x(t) + (dt/6) * (k1 + 2*k2 + 2*k3 + k4)
 			posChange := vector.Vector{0, 0, 0}
 			posChange.Add(k1Vel[i])
 
@@ -238,47 +509,61 @@ func (w *World) tick() {
 			posChange.Add(temp)
 
 			posChange.Add(k4Vel[i])
-			posChange.MultScalar(1.0 / 6.0)
+			posChange.MultScalar(dt / 6.0)
 
 			body.Pos.Add(posChange)
 		}
 
 		// Handle collisions and escapes
 		var escaping []*body.Body
-		var colliding []map[*body.Body]bool
+		// Collision groups are kept in slices rather than iterated maps so
+		// that merges always happen in the same order for the same seed
+		var colliding [][]*body.Body
+		groupOf := make(map[*body.Body]int)
 
 		addCollision := func(body1, body2 *body.Body) {
-			added := false
-			for _, groups := range colliding {
-				if _, ok := groups[body1]; ok {
-					groups[body2] = true
-					added = true
-				}
-				if _, ok := groups[body2]; ok {
-					groups[body1] = true
-					added = true
+			g1, ok1 := groupOf[body1]
+			g2, ok2 := groupOf[body2]
+			switch {
+			case ok1 && ok2:
+				if g1 != g2 {
+					for _, b := range colliding[g2] {
+						groupOf[b] = g1
+					}
+					colliding[g1] = append(colliding[g1], colliding[g2]...)
+					colliding[g2] = nil
 				}
-			}
-			if !added {
-				newmap := make(map[*body.Body]bool)
-				newmap[body1] = true
-				newmap[body2] = true
-				colliding = append(colliding, newmap)
+			case ok1:
+				groupOf[body2] = g1
+				colliding[g1] = append(colliding[g1], body2)
+			case ok2:
+				groupOf[body1] = g2
+				colliding[g2] = append(colliding[g2], body1)
+			default:
+				groupOf[body1] = len(colliding)
+				groupOf[body2] = len(colliding)
+				colliding = append(colliding, []*body.Body{body1, body2})
 			}
 		}
 
+		// Test particles never collide with each other, so only pairs with
+		// at least one massive body need checking
+		massive := w.massiveBodies()
+		moments := w.massMoments()
 		for _, body := range w.bodies {
 			// Check if body is 1) higher than escape velocity and 2) is more more
 			// than 2X screens from center.
-			if w.escaped(body) {
+			if w.escaped(body, moments) {
 				escaping = append(escaping, body)
 			} else {
-				for _, body2 := range w.bodies {
+				for _, body2 := range massive {
 					if body == body2 {
 						continue
 					}
 					if body.Collides(body2) {
 						addCollision(body, body2)
+					} else if w.events != nil {
+						w.events.pair(w, body, body2)
 					}
 				}
 			}
@@ -287,25 +572,59 @@ func (w *World) tick() {
 		// Handle escaping bodies
 		for _, escaper := range escaping {
 			fmt.Printf("%v: ESCAPED: %v\n", w.worldTime(), escaper)
+			w.logEvent("escape", &escapeEntry{Body: logBody(escaper)})
+			if w.flyby != nil {
+				w.flyby.escaped[escaper.Id] = true
+			}
+			if w.recorder != nil {
+				if err := w.recorder.removed(w, escaper, trajectory.Escaped, 0); err != nil {
+					w.recordError(err)
+				}
+			}
 			w.removeBody(escaper)
 		}
 
 		// Handle collisions
 		for _, group := range colliding {
 			var big *body.Body
-			for b, _ := range group {
-				if big == nil || b.Radius > big.Radius {
+			for _, b := range group {
+				// Massive bodies always absorb test particles
+				if big == nil || (big.TestParticle && !b.TestParticle) ||
+					(big.TestParticle == b.TestParticle && b.Radius > big.Radius) {
 					big = b
 				}
 			}
-			for small, _ := range group {
+			for _, small := range group {
 				if small != big {
+					w.mergers.Record(w.elapsed, big, small)
 					big.CollideWith(small)
 					fmt.Printf("%v: COLLISION: %v\n", w.worldTime(), big)
+					w.logEvent("collision", &collisionEntry{Into: logBody(big), Absorbed: logBody(small)})
+					if w.recorder != nil {
+						if err := w.recorder.removed(w, small, trajectory.Merged, big.Id); err != nil {
+							w.recordError(err)
+						}
+					}
 					w.removeBody(small)
 				}
 			}
 		}
+
+		if w.histogram != nil {
+			w.histogram.sample(w)
+		}
+		if w.flyby != nil {
+			w.flyby.check(w)
+		}
+		if w.recorder != nil {
+			if err := w.recorder.sample(w); err != nil {
+				w.recordError(err)
+			}
+		}
+		if w.events != nil {
+			w.events.endStep()
+			w.events.diagnose(w)
+		}
 	}
 }
 
@@ -313,10 +632,12 @@ func (w *World) tick() {
 // Calculate accelerations for all bodies and store in the provided slice
 func (w *World) calculateAllAccelerations(accelerations []vector.Vector) {
 	channels := make([]chan vector.Vector, len(w.bodies))
+	massive := w.massiveBodies()
+	law := w.forceLaw()
 
 	for i, body := range w.bodies {
 		channels[i] = make(chan vector.Vector)
-		go w.calculateAcceleration(body, channels[i])
+		go w.calculateAcceleration(body, massive, law, channels[i])
 	}
 
 	for i, ch := range channels {
@@ -332,32 +653,33 @@ func (w *World) calculateAllAccelerations(accelerations []vector.Vector) {
 }
 
 // Apply half step for midpoint calculations
-func (w *World) applyHalfStep(initialPos, initialVel, velDelta, accDelta []vector.Vector) {
+func (w *World) applyHalfStep(initialPos, initialVel, velDelta, accDelta []vector.Vector, dt float64) {
+	h := 0.5 * dt
 	for i, body := range w.bodies {
 		// Position = initial + 0.5 * velocity * dt
-		body.Pos.X = initialPos[i].X + 0.5 * velDelta[i].X
-		body.Pos.Y = initialPos[i].Y + 0.5 * velDelta[i].Y
-		body.Pos.Z = initialPos[i].Z + 0.5 * velDelta[i].Z
+		body.Pos.X = initialPos[i].X + h * velDelta[i].X
+		body.Pos.Y = initialPos[i].Y + h * velDelta[i].Y
+		body.Pos.Z = initialPos[i].Z + h * velDelta[i].Z
 
 		// Velocity = initial + 0.5 * acceleration * dt
-		body.Vel.X = initialVel[i].X + 0.5 * accDelta[i].X
-		body.Vel.Y = initialVel[i].Y + 0.5 * accDelta[i].Y
-		body.Vel.Z = initialVel[i].Z + 0.5 * accDelta[i].Z
+		body.Vel.X = initialVel[i].X + h * accDelta[i].X
+		body.Vel.Y = initialVel[i].Y + h * accDelta[i].Y
+		body.Vel.Z = initialVel[i].Z + h * accDelta[i].Z
 	}
 }
 
 // Apply full step for final k4 calculation
-func (w *World) applyFullStep(initialPos, initialVel, velDelta, accDelta []vector.Vector) {
+func (w *World) applyFullStep(initialPos, initialVel, velDelta, accDelta []vector.Vector, dt float64) {
 	for i, body := range w.bodies {
 		// Position = initial + velocity * dt
-		body.Pos.X = initialPos[i].X + velDelta[i].X
-		body.Pos.Y = initialPos[i].Y + velDelta[i].Y
-		body.Pos.Z = initialPos[i].Z + velDelta[i].Z
+		body.Pos.X = initialPos[i].X + dt*velDelta[i].X
+		body.Pos.Y = initialPos[i].Y + dt*velDelta[i].Y
+		body.Pos.Z = initialPos[i].Z + dt*velDelta[i].Z
 
 		// Velocity = initial + acceleration * dt
-		body.Vel.X = initialVel[i].X + accDelta[i].X
-		body.Vel.Y = initialVel[i].Y + accDelta[i].Y
-		body.Vel.Z = initialVel[i].Z + accDelta[i].Z
+		body.Vel.X = initialVel[i].X + dt*accDelta[i].X
+		body.Vel.Y = initialVel[i].Y + dt*accDelta[i].Y
+		body.Vel.Z = initialVel[i].Z + dt*accDelta[i].Z
 	}
 }
 
@@ -374,6 +696,27 @@ func (w *World) resetToInitial(initialPos, initialVel []vector.Vector) {
 	}
 }
 
+// Write the merger tree to path, as Graphviz DOT if it ends in .dot and
+// JSON otherwise
+func (w World) exportMergers(path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("Unable to write merger tree: %v\n", err)
+		return
+	}
+	defer file.Close()
+	if strings.HasSuffix(path, ".dot") {
+		err = w.mergers.WriteDOT(file)
+	} else {
+		err = w.mergers.WriteJSON(file)
+	}
+	if err != nil {
+		fmt.Printf("Unable to write merger tree: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote %v mergers to %v\n", len(w.mergers), path)
+}
+
 func iPow(a, b int) int {
 	var result int = 1
 
@@ -389,36 +732,37 @@ func iPow(a, b int) int {
 	return result
 }
 
-func solarSystem(w, h int) *World {
-	world := &World{
-		scale:   1.0,
-		mpp:     5.5e8,
-		spt:     600,
-		running: true,
-		elapsed: 0,
-		bodies:  make([]*body.Body, 6),
-		width:   w,
-		height:  h,
-		mag:     1.0,
-	}
-
-	world.bodies[0] = body.NewBody("Sol", 0, 0, 696_340_000, 1.9885e30, 0.0, 0.0, sprites["sun"])
-	world.bodies[0].Id = "Mother"
-	world.bodies[1] = body.NewBody("Mercury", 46e9, 0, 2_439_700, 0.33011e24, 0.0, 58.98e3, sprites["mercury"])
-	world.bodies[2] = body.NewBody("Venus", 0, 107.48e9, 6_051_800, 4.86750e24, -35.26e3, 0.0, sprites["venus"])
-	world.bodies[3] = body.NewBody("Mars", 0, -206.62e9, 3_389_500, 0.64171e24, 26.50e3, 0.0, sprites["mars"])
-	earth := body.NewBody("Earth", -147.09e9, 0, 6_371_000, 5.9724e24, 0.0, -30.29e3, sprites["earth"])
-	world.bodies[4] = earth
-	luna := body.NewBody("Luna", earth.Pos.X-0.3633e9, 0, 1_737_400, 0.07346e24, 0.0, earth.Vel.Y-1.082e3, sprites["luna"])
-	world.bodies[5] = luna
+// Spread of the orbits made by the random generators: eccentricities and
+// inclinations in radians are drawn uniformly up to these
+type orbitSpread struct {
+	ecc float64
+	inc float64
+}
 
-	for _, body := range world.bodies {
-		fmt.Printf("%v\n", body)
+// Orbit with semi-major axis a, random orientation and phase
+func (s orbitSpread) elements(a float64, rng *math_rand.Rand) body.Elements {
+	return body.Elements{
+		A:    a,
+		E:    rng.Float64() * s.ecc,
+		I:    rng.Float64() * s.inc,
+		Node: rng.Float64() * math.Pi * 2,
+		Peri: rng.Float64() * math.Pi * 2,
+		M:    rng.Float64() * math.Pi * 2,
 	}
-	return world
 }
 
-func randomWithMoons(w, h, n, m int, df float64) *World {
+// Orbits of the moons in moons MODE
+type moonSpec struct {
+	spread orbitSpread
+	// fraction of its planet's Hill radius a moon's apoapsis stays inside
+	hill float64
+}
+
+// Planets on orbits from spread with m moons each, at random phases around
+// their planet. A moon's periapsis is up to 50 pixels above its planet's
+// surface, with its apoapsis inside the fraction of the planet's Hill
+// radius from moons; moons of planets too light to hold them are reported.
+func randomWithMoons(w, h, n, m int, df float64, spread orbitSpread, moons moonSpec, rng *math_rand.Rand) *World {
 	fmt.Printf("Making %v planets with %v moons each\n", n, m)
 	world := &World{
 		scale:   0.1,
@@ -435,47 +779,47 @@ func randomWithMoons(w, h, n, m int, df float64) *World {
 	center := world.bodies[0]
 	fmt.Printf("%v\n", center)
 	maxDistance := math.Sqrt(float64(iPow(world.width, 2)+iPow(world.height, 2))) * 2.0
+	var unstable []string
 	bi := 1
 	for i := 0; i < n; i++ {
-		distance := 200.0 + math_rand.Float64()*maxDistance*df
-		theta := math_rand.Float64() * math.Pi * 2
-		pos := vector.New2DVector(-distance*math.Cos(theta)*world.mpp, -distance*math.Sin(theta)*world.mpp)
-		circularOrbitVel := math.Sqrt(G * center.Mass / pos.Magnitude())
-		u := pos.Unit()
-		un := u.Normal2D()
-		vel := vector.Vector{un.X, un.Y, un.Z}
-		vel.MultScalar(circularOrbitVel)
-		mass := math_rand.Float64() * 1e26
-		radius := float64(8+math_rand.Intn(8)) * world.mpp
-		world.bodies[bi] = body.NewBody(fmt.Sprintf("P%v", i), pos.X, pos.Y, radius, mass, vel.X, vel.Y, randomPlanetSprite())
-		fmt.Printf("%v\n", world.bodies[bi])
+		distance := 200.0 + rng.Float64()*maxDistance*df
+		mass := rng.Float64() * 1e26
+		radius := float64(8+rng.Intn(8)) * world.mpp
+		el := spread.elements(distance*world.mpp, rng)
+		planet := body.NewOrbitingBody(fmt.Sprintf("P%v", i), center, el, radius, mass, randomPlanetSprite(rng))
+		world.bodies[bi] = planet
+		fmt.Printf("%v\n", planet)
 		bi += 1
+		hill := moons.hill * el.HillRadius(mass, center.Mass)
 		for j := 0; j < m; j++ {
-			//moon
-			d := radius + float64(10+math_rand.Intn(40))*world.mpp
-			// moon vel
-			moonOrbVel := math.Sqrt(G * mass / d)
-			var sign float64
-			if math_rand.Intn(2) == 1 {
-				sign = 1
-			} else {
-				sign = -1
+			mm := 1e5 * rng.Float64()
+			mr := float64(1+rng.Intn(4)) * world.mpp
+			mel := moons.spread.elements(0, rng)
+			lo := (radius + 2*mr) / (1 - mel.E)
+			hi := math.Min((radius+50*world.mpp)/(1-mel.E), hill/(1+mel.E))
+			mel.A = lo
+			if hi > lo {
+				mel.A += rng.Float64() * (hi - lo)
+			}
+			moon := body.NewOrbitingBody(fmt.Sprintf("P%vM%v", i, j), planet, mel, mr, mm, randomPlanetSprite(rng))
+			world.bodies[bi] = moon
+			fmt.Printf("%v\n", moon)
+			if mel.A*(1+mel.E) > hill {
+				unstable = append(unstable, fmt.Sprintf("%v (apoapsis %.3g m, %v of the Hill radius %.3g m)",
+					moon.Name, mel.A*(1+mel.E), moons.hill, hill))
 			}
-			mu := vector.Vector{0, sign, 0}
-			mv := vector.Vector{vel.X, vel.Y, vel.Z}
-			mu.MultScalar(moonOrbVel)
-			mv.Add(mu)
-			mm := 1e5 * math_rand.Float64()
-			mr := float64(1+math_rand.Intn(4)) * world.mpp
-			world.bodies[bi] = body.NewBody(fmt.Sprintf("P%vM%v", i, j), pos.X-sign*d, pos.Y, mr, mm, mv.X, mv.Y, randomPlanetSprite())
-			fmt.Printf("%v\n", world.bodies[bi])
 			bi += 1
 		}
 	}
+	fmt.Printf("MOONS: %v of %v moons stay within %v of their planet's Hill radius\n", n*m-len(unstable), n*m,
+		moons.hill)
+	for _, u := range unstable {
+		fmt.Printf("MOONS: unstable %v\n", u)
+	}
 	return world
 }
 
-func randomWorld(w, h, n int, pf float64, df float64) *World {
+func randomWorld(w, h, n int, pf float64, df float64, spread orbitSpread, rng *math_rand.Rand) *World {
 	world := &World{
 		scale:   0.3,
 		mpp:     5e5,
@@ -493,50 +837,76 @@ func randomWorld(w, h, n int, pf float64, df float64) *World {
 	maxDistance := math.Sqrt(float64(iPow(world.width, 2)+iPow(world.height, 2))) / 2.0
 	maxDistance *= df
 	for i := 1; i < n+1; i++ {
-		distance := 200.0 + math_rand.Float64()*maxDistance
-		theta := math_rand.Float64() * math.Pi * 2
-		pos := vector.New2DVector(-distance*math.Cos(theta)*world.mpp, -distance*math.Sin(theta)*world.mpp)
-		circularOrbitVel := math.Sqrt(G * center.Mass / pos.Magnitude())
-		u := pos.Unit()
-		un := u.Normal2D()
-		vel := vector.Vector{un.X, un.Y, un.Z}
-		vel.MultScalar(circularOrbitVel)
-
-		vel.X *= (1.0 - (pf / 2.0) + math_rand.Float64()*pf)
-		vel.Y *= (1.0 - (pf / 2.0) + math_rand.Float64()*pf)
-
-		baseMass := 1e22
-		baseRadius := 10.0
+		distance := 200.0 + rng.Float64()*maxDistance
+		el := spread.elements(distance*world.mpp, rng)
+
+		var b *body.Body
 		if i > n/2 {
-			baseMass = 1e7
-			baseRadius = 4.0
+			b = body.NewOrbitingTestParticle(fmt.Sprintf("P%v", i), center, el,
+				(1.0+rng.Float64())*4.0*world.mpp, randomPlanetSprite(rng))
+		} else {
+			b = body.NewOrbitingBody(fmt.Sprintf("P%v", i), center, el,
+				(1.0+rng.Float64())*10.0*world.mpp,
+				1e22*rng.Float64(), randomPlanetSprite(rng))
 		}
-		world.bodies[i] = body.NewBodyVector(fmt.Sprintf("P%v", i), pos, vel,
-			(1.0+math_rand.Float64())*baseRadius*world.mpp,
-			baseMass*math_rand.Float64(), randomPlanetSprite())
-		fmt.Printf("%v\n", world.bodies[i])
+		b.Vel.X *= (1.0 - (pf / 2.0) + rng.Float64()*pf)
+		b.Vel.Y *= (1.0 - (pf / 2.0) + rng.Float64()*pf)
+		world.bodies[i] = b
+		fmt.Printf("%v\n", b)
 	}
 	return world
 }
 
+// Add a cloud of n massless test particles orbiting the central body, e.g.
+// an asteroid belt or debris disk.
+func addTestParticles(world *World, n int, df float64, spread orbitSpread, rng *math_rand.Rand) {
+	center := world.bodies[0]
+	maxDistance := math.Sqrt(float64(iPow(world.width, 2)+iPow(world.height, 2))) / 2.0
+	maxDistance *= df
+	for i := 0; i < n; i++ {
+		distance := 200.0 + rng.Float64()*maxDistance
+		world.bodies = append(world.bodies, body.NewOrbitingTestParticle(fmt.Sprintf("T%v", i), center,
+			spread.elements(distance*world.mpp, rng), MinRadius*world.mpp, sprites["circle"]))
+	}
+	fmt.Printf("Added %v test particles\n", n)
+}
+
 func usage() string {
 	return `Usage:
-	nbody-go [-hPC -d<dimensions> -s=<spt> -p=<pf> -r=<df> -n=<numBodies> -m=<numMoons> -M=<mf>] MODE
-Run N-Body simulation in mode MODE
+	nbody-go help [MODE]
+	nbody-go [options] replay FILE
+	nbody-go [options] MODE [FILE]
+	nbody-go [options] --resume=<file>
+Run N-Body simulation in mode MODE, or play back a recorded trajectory
 Arguments:
-  MODE        mode of the simulation, one of random, moons, solar
+  MODE        mode of the simulation, one of ` + strings.Join(generatorNames(), ", ") + `
+  FILE        scenario file to load in file MODE, see docs/scenario.md, or trajectory file to replay
 Options:
 	-h --help
 	-d=<dimensions>, --dimensions=<dimensions>  dimensions of screen in pixels [default: 1024x1024]
 	-P        Start paused
 	-C        Use plain white circle as planet graphic instead of random ones in moons and random MODE
-	-s=<spt>  Seconds of world time to calculate per UI tick
-	-p=<pf>   Perturbation factor for random world generation [default: 0.2]
-	-r=<df>   Distance factor for random world generation [default: 1.0]
+	-s=<spt>  Integration steps to calculate per UI tick
 	-M=<mf>   For high DPI screens, scale up window by this amount [default: 1.0]
-	-n=<numBodies>, --number=<numBodies>      Number of bodies to start [default: 60]
-	-m=<numMoons>, --moons=<numMoons>         Number of moons per body [default: 3]
-`
+	--potential=<spec>  External potentials, e.g. "nfw,m=1e41,rs=3e19;uniform,gy=-9.8"
+	--law=<law>         Force law between bodies: newton, coulomb,q=<C>, yukawa,lambda=<m>, mond,a0=<m/s^2>, power,n=<n>
+	--raw-frame         Do not shift the world into the zero momentum barycentric frame
+	--follow-barycenter  Keep the camera centered on the barycenter
+	--seed=<seed>       Seed for the random world generators, random when not given
+	--mergers=<file>    Write the merger tree to file on exit, Graphviz DOT for .dot files and JSON otherwise
+	--histogram=<file>  Write a CSV histogram of the test particles' semi-major axes around the heaviest body, sampled over the run, on exit
+	--bins=<n>          Number of bins of the semi-major axis histogram [default: 100]
+	--belt=<au>         Inner and outer semi-major axis of the asteroid belt in belt MODE and of the --histogram [default: 2.0,3.5]
+	--resume=<file>     Continue the run saved in a checkpoint, ignoring the options that build the world except --flyby
+	--checkpoint=<file>  File the S key and --checkpoint-every write checkpoints to [default: checkpoint.json]
+	--checkpoint-every=<s>  Also write a checkpoint every so many simulated seconds
+	--record=<file>     Record the positions and velocities of the bodies to a binary trajectory file
+	--record-every=<s>  Simulated seconds between recorded frames, one frame per UI tick when not given
+	--record-float32    Record coordinates in single precision, half the size
+	--log=<file>        Write a JSON Lines log of the world, collisions, escapes, close approaches, checkpoints and diagnostics
+	--log-every=<s>     Simulated seconds between diagnostics in the log, one per UI tick when not given
+	--flyby=<spec>      Send a star through the world in any MODE, e.g. "star,m=2e30,b=1.5e12,v=3000,inc=30", and report the bodies it ejected, captured or disturbed
+` + generatorOptions()
 }
 
 func run() {
@@ -544,6 +914,16 @@ func run() {
 	if opterr != nil {
 		panic(opterr)
 	}
+	if help, _ := options.Bool("help"); help {
+		mode, _ := options.String("MODE")
+		text, err := generatorHelp(mode)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		fmt.Print(text)
+		return
+	}
 	dims, _ := options.String("--dimensions")
 	width, height := func() (int, int) {
 		elems := strings.Split(dims, "x")
@@ -551,22 +931,99 @@ func run() {
 		h, _ := strconv.Atoi(elems[1])
 		return w, h
 	}()
-	numBodies, _ := options.Int("--number")
-	numMoons, _ := options.Int("--moons")
-	pf, _ := options.Float64("-p")
-	df, _ := options.Float64("-r")
 	mode, _ := options.String("MODE")
+	var err error
 	spt, _ := options.Int("-s")
 	paused, _ := options.Bool("-P")
 	circleMode, _ = options.Bool("-C")
 	mf, _ := options.Float64("-M")
+	mergersFile, _ := options.String("--mergers")
+	histogramFile, _ := options.String("--histogram")
+	resumeFile, _ := options.String("--resume")
+	recordFile, _ := options.String("--record")
+	recordEvery := 0.0
+	if every, ok := options["--record-every"].(string); ok {
+		recordEvery, err = strconv.ParseFloat(every, 64)
+		if err != nil || recordEvery <= 0 {
+			fmt.Printf("Invalid --record-every: need a positive number of seconds\n")
+			os.Exit(2)
+		}
+	}
+	logFile, _ := options.String("--log")
+	logEvery := 0.0
+	if every, ok := options["--log-every"].(string); ok {
+		logEvery, err = strconv.ParseFloat(every, 64)
+		if err != nil || logEvery <= 0 {
+			fmt.Printf("Invalid --log-every: need a positive number of seconds\n")
+			os.Exit(2)
+		}
+	}
+	recordSize := 8
+	if single, _ := options.Bool("--record-float32"); single {
+		recordSize = 4
+	}
+	checkpointFile, _ := options.String("--checkpoint")
+	checkpointEvery := 0.0
+	if every, ok := options["--checkpoint-every"].(string); ok {
+		checkpointEvery, err = strconv.ParseFloat(every, 64)
+		if err != nil || checkpointEvery <= 0 {
+			fmt.Printf("Invalid --checkpoint-every: need a positive number of seconds\n")
+			os.Exit(2)
+		}
+	}
+	bins, _ := options.Int("--bins")
+	if bins < 1 {
+		fmt.Printf("Invalid --bins: need at least one bin\n")
+		os.Exit(2)
+	}
+	beltSpec, _ := options.String("--belt")
+	beltInner, beltOuter, err := parseBelt(beltSpec)
+	if err != nil {
+		fmt.Printf("Invalid --belt: %v\n", err)
+		os.Exit(2)
+	}
+	rawFrame, _ := options.Bool("--raw-frame")
+	followBarycenter, _ := options.Bool("--follow-barycenter")
+	scenarioFile, _ := options.String("FILE")
+	potentialSpec, _ := options.String("--potential")
+	potentials, err := parsePotentials(potentialSpec)
+	if err != nil {
+		fmt.Printf("Invalid --potential: %v\n", err)
+		os.Exit(2)
+	}
+	var flybyParams map[string]float64
+	if flybySpec, ok := options["--flyby"].(string); ok {
+		flybyParams, err = parseFlyby(flybySpec)
+		if err != nil {
+			fmt.Printf("Invalid --flyby: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var law body.ForceLaw
+	charge := 0.0
+	if lawSpec, ok := options["--law"].(string); ok {
+		law, charge, err = parseForceLaw(lawSpec)
+		if err != nil {
+			fmt.Printf("Invalid --law: %v\n", err)
+			os.Exit(2)
+		}
+	}
 
-	initRand()
+	seed := randomSeed()
+	if seedOpt, ok := options["--seed"].(string); ok {
+		seed, err = strconv.ParseInt(seedOpt, 10, 64)
+		if err != nil {
+			fmt.Printf("Invalid --seed: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	source := newSource(seed)
+	rng := math_rand.New(source)
 
 	// initialize all the sprites
 	loadSprite("sun", "./images/sun.png")
 	loadSprite("earth", "./images/earth.png")
-	loadSprite("jupiter", "./images/earth.png")
+	loadSprite("jupiter", "./images/jupiter.png")
 	loadSprite("luna", "./images/luna.png")
 	loadSprite("mars", "./images/mars.png")
 	loadSprite("venus", "./images/venus.png")
@@ -585,23 +1042,75 @@ func run() {
 	}
 
 	var world *World
-	if mode == "random" {
-		world = randomWorld(width, height, numBodies, pf, df)
-	} else if mode == "solar" {
-		world = solarSystem(width, height)
-	} else if mode == "moons" {
-		totalBodies := numBodies
-		for (numBodies*numMoons + numBodies) > totalBodies {
-			numBodies -= 1
-		}
-		world = randomWithMoons(width, height, numBodies, numMoons, df)
+	var replay *player
+	cam := camera{Follow: -1}
+	if replaying, _ := options.Bool("replay"); replaying {
+		r, err := trajectory.Open(scenarioFile)
+		if err != nil {
+			fmt.Printf("Unable to replay: %v\n", err)
+			os.Exit(2)
+		}
+		defer r.Close()
+		replay, err = newPlayer(r)
+		if err != nil {
+			fmt.Printf("Unable to replay %v: %v\n", scenarioFile, err)
+			os.Exit(2)
+		}
+		world = replayWorld(replay, mf)
+		fmt.Printf("Replaying %v from %v\nSEED: %v\n", scenarioFile, world.worldTime(), world.seed)
+	} else if resumeFile != "" {
+		world, cam, err = loadCheckpoint(resumeFile)
+		if err != nil {
+			fmt.Printf("Unable to resume: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Printf("Resumed %v at %v\nSEED: %v\n", resumeFile, world.worldTime(), world.seed)
+		if flybyParams != nil {
+			if err := startFlyby(world, flybyParams); err != nil {
+				fmt.Printf("Invalid --flyby: %v\n", err)
+				os.Exit(2)
+			}
+		}
 	} else {
-		fmt.Printf("MODE %v is not valid\n", mode)
-		fmt.Print(usage())
-		os.Exit(2)
-	}
+		fmt.Printf("SEED: %v\n", seed)
+		ctx := genContext{width: width, height: height, file: scenarioFile, rng: rng, beltInner: beltInner,
+			beltOuter: beltOuter}
+		if _, ok := generator.Lookup(mode); !ok {
+			fmt.Printf("MODE %v is not valid\n", mode)
+			fmt.Print(usage())
+			os.Exit(2)
+		}
+		world, err = generateWorld(mode, ctx, generatorValues(mode, options))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
 
-	world.mag = mf
+		if err := setUpWorld(world, potentials, rawFrame, flybyParams); err != nil {
+			fmt.Printf("Invalid --flyby: %v\n", err)
+			os.Exit(2)
+		}
+
+		world.seed, world.source = seed, source
+		world.mag = mf
+		world.followBarycenter = world.followBarycenter || followBarycenter
+		if law != nil {
+			world.law = law
+		}
+		if charge != 0 {
+			assignCharges(world, charge, rng)
+		}
+	}
+	if histogramFile != "" && world.histogram == nil && replay == nil {
+		world.histogram = newSMAHistogram(world, beltInner, beltOuter, bins, 10*world.timestep())
+	}
+	for _, p := range world.potentials {
+		fmt.Printf("POTENTIAL: %v\n", p)
+	}
+	fmt.Printf("FORCE LAW: %v\n", world.forceLaw())
+	if unchargedCoulomb(world) {
+		fmt.Printf("WARNING: no body has a charge so nothing interacts, give one with --law coulomb,q=<C>\n")
+	}
 
 	if spt > 0 {
 		world.spt = spt
@@ -609,10 +1118,30 @@ func run() {
 	if paused {
 		world.running = false
 	}
+	if recordFile != "" && replay == nil {
+		if recordEvery == 0 {
+			recordEvery = float64(world.spt) * world.timestep()
+		}
+		world.recorder, err = newRecorder(world, recordFile, recordEvery, recordSize)
+		if err != nil {
+			fmt.Printf("Unable to record trajectory: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if logFile != "" && replay == nil {
+		if logEvery == 0 {
+			logEvery = float64(world.spt) * world.timestep()
+		}
+		world.events, err = newEventLog(world, logFile, logEvery, mode, resumeFile)
+		if err != nil {
+			fmt.Printf("Unable to write event log: %v\n", err)
+			os.Exit(2)
+		}
+	}
 
 	cfg := pixelgl.WindowConfig{
 		Title:  "N-Body Problem",
-		Bounds: pixel.R(0, 0, float64(width)*world.mag, float64(height)*world.mag),
+		Bounds: pixel.R(0, 0, float64(world.width)*world.mag, float64(world.height)*world.mag),
 		VSync:  true,
 	}
 
@@ -625,10 +1154,25 @@ func run() {
 	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
 	infoTxt := text.New(pixel.V(win.Bounds().Max.X-200*world.mag, win.Bounds().Max.Y-20*world.mag), basicAtlas)
 
-	followBody := -1
+	followBody := cam.Follow
 	center := vector.Vector{win.Bounds().Center().X, win.Bounds().Center().Y, 0}
 	offset := center
+	if resumeFile != "" {
+		offset = cam.Offset
+	}
 	var closest *body.Body
+	showContours := cam.Contours
+	saveCheckpoint := func() {
+		if err := world.saveCheckpoint(checkpointFile, camera{followBody, offset, showContours}); err != nil {
+			fmt.Printf("Unable to write checkpoint: %v\n", err)
+			return
+		}
+		fmt.Printf("%v: CHECKPOINT: %v\n", world.worldTime(), checkpointFile)
+		world.logEvent("checkpoint", &checkpointEntry{File: checkpointFile})
+	}
+	nextCheckpoint := world.elapsed + checkpointEvery
+	var energy, momentum float64
+	var infoAt time.Time
 
 	for !win.Closed() {
 
@@ -639,6 +1183,7 @@ func run() {
 
 		// switch center from body to body
 		if win.JustPressed(pixelgl.KeyN) {
+			world.followBarycenter = false
 			if followBody == -1 {
 				followBody = 1
 			} else {
@@ -652,16 +1197,52 @@ func run() {
 		// Recenter
 		if win.JustPressed(pixelgl.KeyC) {
 			followBody = -1
+			world.followBarycenter = false
 			offset = center
 		}
 
+		// Keep the barycenter in the middle of the screen
+		if win.JustPressed(pixelgl.KeyB) {
+			followBody = -1
+			world.followBarycenter = !world.followBarycenter
+		}
+
+		// Toggle potential contours
+		if win.JustPressed(pixelgl.KeyU) {
+			showContours = !showContours
+		}
+
+		// Save a checkpoint to resume from
+		if win.JustPressed(pixelgl.KeyS) && replay == nil {
+			saveCheckpoint()
+		}
+
+		// Toggle the view co-rotating with the two heaviest bodies
+		if win.JustPressed(pixelgl.KeyR) {
+			world.corotate = !world.corotate
+		}
+
+		// Play the recording backwards or forwards
+		if replay != nil && win.JustPressed(pixelgl.KeyLeft) {
+			replay.reverse, world.running = true, true
+		}
+		if replay != nil && win.JustPressed(pixelgl.KeyRight) {
+			replay.reverse, world.running = false, true
+		}
+
 		// Turn off closest vec, accel and info display
 		if win.JustPressed(pixelgl.MouseButtonRight) {
 			closest = nil
 		}
 
-		// Get details on a body
-		if win.JustPressed(pixelgl.MouseButtonLeft) {
+		// Seek on the timeline or get details on a body
+		onTimeline := false
+		if replay != nil && win.JustPressed(pixelgl.MouseButtonLeft) {
+			if onTimeline, err = replay.click(world, win.Bounds(), win.MousePosition()); err != nil {
+				fmt.Printf("Unable to seek: %v\n", err)
+			}
+		}
+		if win.JustPressed(pixelgl.MouseButtonLeft) && !onTimeline {
 			closest = nil
 			mouseCoords := win.MousePosition()
 			mouseCoordsVec := vector.Vector{mouseCoords.X, mouseCoords.Y, 0}
@@ -696,15 +1277,36 @@ func run() {
 		world.scale *= math.Pow(1.2, win.MouseScroll().Y)
 		win.Clear(colornames.Black)
 		mat := pixel.IM
+		world.updateFrame()
 
 		if followBody >= 0 && followBody < len(world.bodies) {
 			offset = vector.Vector{center.X, center.Y, center.Z}
 			offset.Sub(world.worldToScreen(&world.bodies[followBody].Pos))
+		} else if world.followBarycenter {
+			barycenter, _ := world.barycenter()
+			offset = vector.Vector{center.X, center.Y, center.Z}
+			offset.Sub(world.worldToScreen(&barycenter))
 		}
-		if len(world.bodies) <= 0 {
+		if len(world.bodies) <= 0 && replay == nil {
 			fmt.Println("There are no more bodies, ending sim...")
+			if mergersFile != "" {
+				world.exportMergers(mergersFile)
+			}
+			if histogramFile != "" {
+				world.exportHistogram(histogramFile)
+			}
+			if world.flyby != nil && !world.flyby.reported {
+				world.flyby.report(world)
+			}
+			world.closeRecording(recordFile)
+			world.closeLog(logFile)
 			os.Exit(3)
 		}
+		if showContours {
+			imd := imdraw.New(nil)
+			world.drawContours(imd, win.Bounds(), offset)
+			imd.Draw(win)
+		}
 		for _, body := range world.bodies {
 			sprite := body.Sprite
 			if sprite == nil {
@@ -717,17 +1319,37 @@ func run() {
 			}
 			sf := brp / spriteSize
 			bodyMat := mat.ScaledXY(pixel.ZV, pixel.V(sf*world.mag, sf*world.mag))
+			if body.Spin.Z != 0 {
+				bodyMat = bodyMat.Rotated(pixel.ZV, math.Mod(body.AngularVelocity().Z*world.elapsed, 2*math.Pi))
+			}
 			screenPos := world.worldToScreen(&body.Pos)
 			screenPos.Add(offset)
 			bodyMat = bodyMat.Moved(pixel.V(screenPos.X, screenPos.Y))
 			sprite.Draw(win, bodyMat)
 		}
+		if replay != nil {
+			imd := imdraw.New(nil)
+			replay.drawTimeline(imd, world, win.Bounds())
+			imd.Draw(win)
+		}
 		// Update info text
 		infoTxt.Clear()
 		fmt.Fprintf(infoTxt, "N: %v\n", len(world.bodies))
 		fmt.Fprintf(infoTxt, "t: %v\n", world.worldTime())
+		if world.epoch != 0 {
+			fmt.Fprintf(infoTxt, "%v\n", horizons.Time(world.epoch+world.elapsed/86400).Format("2006-01-02 15:04"))
+		}
 		fmt.Fprintf(infoTxt, "S: %4.2f\n", world.scale)
-		fmt.Fprintf(infoTxt, "dt: %v\n", world.spt)
+		fmt.Fprintf(infoTxt, "dt: %v\n", float64(world.spt)*world.timestep())
+		if replay != nil && replay.reverse {
+			fmt.Fprintf(infoTxt, "Reverse\n")
+		}
+		if time.Since(infoAt) >= InfoRefresh {
+			energy, momentum = world.energy(), world.angularMomentum().Magnitude()
+			infoAt = time.Now()
+		}
+		fmt.Fprintf(infoTxt, "E: %5.2e\n", energy)
+		fmt.Fprintf(infoTxt, "L: %5.2e\n", momentum)
 		// Add on clicked body info
 		if closest != nil {
 			// Add Vel and Acc vectors
@@ -742,22 +1364,57 @@ func run() {
 			imd.Push(pixel.V(closestPos.X, closestPos.Y), pixel.V(endVel.X, endVel.Y))
 			imd.Line(2)
 			imd.Draw(win)
-			// acceleration
-			imd.Color = colornames.Green
-			acc := vector.MultScalar(closest.Acc.Unit(), 40)
-			endAcc := vector.Add(closestPos, acc)
-			imd.Push(pixel.V(closestPos.X, closestPos.Y), pixel.V(endAcc.X, endAcc.Y))
-			imd.Line(2)
-			imd.Draw(win)
+			// acceleration, not recorded in replays
+			if replay == nil {
+				imd.Color = colornames.Green
+				acc := vector.MultScalar(closest.Acc.Unit(), 40)
+				endAcc := vector.Add(closestPos, acc)
+				imd.Push(pixel.V(closestPos.X, closestPos.Y), pixel.V(endAcc.X, endAcc.Y))
+				imd.Line(2)
+				imd.Draw(win)
+			}
 
-			fmt.Fprintf(infoTxt, "\n%v:\n", closest.Name)
+			fmt.Fprintf(infoTxt, "\n%v (#%v):\n", closest.Name, closest.Id)
 			fmt.Fprintf(infoTxt, "P: (%5.2e,%5.2e)\n", closest.Pos.X, closest.Pos.Y)
 			fmt.Fprintf(infoTxt, "V: (%5.2e,%5.2e)\n", closest.Vel.X, closest.Vel.Y)
-			fmt.Fprintf(infoTxt, "A: (%5.2e,%5.2e)\n", closest.Acc.X, closest.Acc.Y)
+			if replay == nil {
+				fmt.Fprintf(infoTxt, "A: (%5.2e,%5.2e)\n", closest.Acc.X, closest.Acc.Y)
+			}
+			if period := closest.SpinPeriod(); !math.IsInf(period, 1) {
+				fmt.Fprintf(infoTxt, "Spin: %.2fh\n", period/3600)
+			}
+			if closest.Charge != 0 {
+				fmt.Fprintf(infoTxt, "Q: %5.2e\n", closest.Charge)
+			}
 		}
 		infoTxt.Draw(win, pixel.IM.Scaled(infoTxt.Orig, world.mag))
 		win.Update()
+		if replay != nil {
+			if err := replay.step(world); err != nil {
+				fmt.Printf("Unable to replay: %v\n", err)
+				world.running = false
+			}
+			continue
+		}
 		world.tick()
+		if checkpointEvery > 0 && world.elapsed >= nextCheckpoint {
+			saveCheckpoint()
+			nextCheckpoint = world.elapsed + checkpointEvery
+		}
+	}
+	if mergersFile != "" && replay == nil {
+		world.exportMergers(mergersFile)
+	}
+	if histogramFile != "" && world.histogram != nil {
+		world.exportHistogram(histogramFile)
+	}
+	if world.flyby != nil && !world.flyby.reported {
+		world.flyby.report(world)
+	}
+	world.closeRecording(recordFile)
+	world.closeLog(logFile)
+	if world.horizons != nil {
+		compareHorizons(world, world.horizons)
 	}
 }
 
@@ -766,7 +1423,7 @@ func main() {
 }
 
 func testMain() {
-	world := randomWorld(1024, 1024, 60, 0.5, 1.0)
+	world := randomWorld(1024, 1024, 60, 0.5, 1.0, orbitSpread{}, newRand(randomSeed()))
 	fmt.Printf("Created world with %v bodies\n", len(world.bodies))
 	start := time.Now()
 	for j := 0; j < 1440*7; j++ {
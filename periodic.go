@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	"strings"
+)
+
+const (
+	// Units of the periodic solutions: one solar mass per body, one au,
+	// and time such that G = 1
+	periodicMass   = 1.98847e30
+	periodicLength = au
+)
+
+// A known periodic solution of the planar equal mass three-body problem in
+// units where G = m = 1, starting from the center of mass at rest
+type periodicOrbit struct {
+	name        string
+	description string
+	pos         []vector.Vector
+	vel         []vector.Vector
+	period      float64
+}
+
+// Collinear start with velocities perpendicular to the line, the form of
+// the Broucke-Henon family of orbits
+func collinearStart(x1, x2, v1, v2 float64) ([]vector.Vector, []vector.Vector) {
+	return []vector.Vector{{x1, 0, 0}, {x2, 0, 0}, {-x1 - x2, 0, 0}},
+		[]vector.Vector{{0, v1, 0}, {0, v2, 0}, {0, -v1 - v2, 0}}
+}
+
+var periodicOrbits = func() []periodicOrbit {
+	// Lagrange: equilateral triangle of circumradius 1 in rigid rotation
+	omega := math.Pow(3, -0.25)
+	var lagrangePos, lagrangeVel []vector.Vector
+	for k := 0; k < 3; k++ {
+		theta := math.Pi/2 + float64(k)*2*math.Pi/3
+		lagrangePos = append(lagrangePos, vector.Vector{math.Cos(theta), math.Sin(theta), 0})
+		lagrangeVel = append(lagrangeVel, vector.Vector{-omega * math.Sin(theta), omega * math.Cos(theta), 0})
+	}
+	// Euler: bodies at -1, 0 and 1 in rigid rotation
+	eulerOmega := math.Sqrt(5) / 2
+
+	// Initial conditions of Broucke's family A (Broucke 1975, "On
+	// relative periodic solutions of the planar general three-body
+	// problem", Celestial Mechanics 12, 439) as given there; the periods
+	// are those at which they close again when integrated
+	a1 := periodicOrbit{name: "broucke-a1", period: 6.28318531,
+		description: "orbit A1 of the Broucke-Henon family (Broucke 1975)"}
+	a1.pos, a1.vel = collinearStart(-0.9892620043, 2.2096177241, 1.9169244185, 0.1910268738)
+	a2 := periodicOrbit{name: "broucke-a2", period: 7.70216,
+		description: "orbit A2 of the Broucke-Henon family (Broucke 1975)"}
+	a2.pos, a2.vel = collinearStart(0.3361300950, 0.7699893804, 1.5324315370, -0.6287350978)
+	// The criss-cross orbit of Hénon's retrograde family (Hénon 1976, "A
+	// family of periodic solutions of the planar three-body problem, and
+	// their stability", Celestial Mechanics 13, 267), with the initial
+	// conditions of Moore (1993)
+	criss := periodicOrbit{name: "henon-criss-cross", period: 6.28302,
+		description: "criss-cross orbit of the Henon family (Henon 1976, Moore 1993)"}
+	criss.pos, criss.vel = collinearStart(1.07590, -0.07095, 0.19509, -1.23187)
+
+	return []periodicOrbit{
+		{
+			name:        "figure-eight",
+			description: "three bodies chasing each other around a figure eight (Chenciner and Montgomery 2000)",
+			pos:         []vector.Vector{{0.97000436, -0.24308753, 0}, {-0.97000436, 0.24308753, 0}, {0, 0, 0}},
+			vel: []vector.Vector{{0.466203685, 0.43236573, 0}, {0.466203685, 0.43236573, 0},
+				{-0.93240737, -0.86473146, 0}},
+			period: 6.32591398,
+		},
+		{
+			name:        "lagrange",
+			description: "equilateral triangle rotating rigidly (Lagrange 1772), unstable for equal masses",
+			pos:         lagrangePos,
+			vel:         lagrangeVel,
+			period:      2 * math.Pi / omega,
+		},
+		{
+			name:        "euler",
+			description: "collinear bodies rotating rigidly about the middle one (Euler 1767), unstable",
+			pos:         []vector.Vector{{-1, 0, 0}, {0, 0, 0}, {1, 0, 0}},
+			vel:         []vector.Vector{{0, -eulerOmega, 0}, {0, 0, 0}, {0, eulerOmega, 0}},
+			period:      2 * math.Pi / eulerOmega,
+		},
+		a1,
+		a2,
+		criss,
+	}
+}()
+
+func findPeriodicOrbit(name string) (periodicOrbit, error) {
+	var names []string
+	for _, o := range periodicOrbits {
+		if o.name == name {
+			return o, nil
+		}
+		names = append(names, o.name)
+	}
+	return periodicOrbit{}, fmt.Errorf("unknown solution %q, one of %v", name, strings.Join(names, ", "))
+}
+
+// Time unit of the periodic solutions in seconds
+func periodicTime() float64 {
+	return math.Sqrt(math.Pow(periodicLength, 3) / (G * periodicMass))
+}
+
+// World for a periodic solution in SI units, stepping so that one period
+// takes steps integration steps.
+func periodicWorld(w, h int, name string, steps int) (*World, error) {
+	o, err := findPeriodicOrbit(name)
+	if err != nil {
+		return nil, err
+	}
+	world := &World{
+		scale:   1.0,
+		spt:     20,
+		running: true,
+		elapsed: 0,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+	}
+	v := periodicLength / periodicTime()
+	for i := range o.pos {
+		world.bodies = append(world.bodies, body.NewBodyVector(fmt.Sprintf("B%v", i+1),
+			vector.MultScalar(o.pos[i], periodicLength), vector.MultScalar(o.vel[i], v),
+			1e-3*periodicLength, periodicMass, sprites["sun"]))
+	}
+	period := o.period * periodicTime()
+	world.dt = period / float64(steps)
+	world.fitToScreen()
+	fmt.Printf("%v: %v\nPeriod %.6g G=m=1 units, %.4g days\n", o.name, o.description, o.period, period/86400)
+	return world, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/seifertd/go/vector"
+	"strings"
+	"testing"
+)
+
+// Each solution must come back to its starting configuration after one
+// period, which also checks the RK4 integration in tick.
+func TestPeriodicOrbitsReturnToStart(t *testing.T) {
+	resetSprites()
+	const steps = 20000
+	families := map[string]bool{}
+	for _, o := range periodicOrbits {
+		families[strings.Split(o.name, "-")[0]] = true
+		world, err := periodicWorld(1024, 1024, o.name, steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		world.spt = 1
+		var start []vector.Vector
+		for _, b := range world.bodies {
+			start = append(start, b.Pos)
+		}
+		for i := 0; i < steps; i++ {
+			world.tick()
+		}
+		if len(world.bodies) != 3 {
+			t.Fatalf("%v: bodies lost: %v", o.name, world.bodies)
+		}
+		for i, b := range world.bodies {
+			if d := b.Pos.DistanceTo(start[i]) / periodicLength; d > 1e-3 {
+				t.Errorf("%v: body %v is %v from its start after one period", o.name, b.Name, d)
+			}
+		}
+	}
+	if !families["broucke"] || !families["henon"] {
+		t.Errorf("orbits of both the Broucke and Henon families expected: %v", families)
+	}
+	if _, err := periodicWorld(1024, 1024, "nope", steps); err == nil {
+		t.Errorf("unknown solutions should be an error")
+	}
+}
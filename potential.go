@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"golang.org/x/image/colornames"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	contourCell   = 16.0
+	contourLevels = 12
+)
+
+// Parse a potential spec of the form kind,key=value,... Multiple potentials
+// are separated by semicolons, e.g. "nfw,m=1e41,rs=3e19;uniform,gy=-9.8".
+func parsePotentials(spec string) ([]body.Potential, error) {
+	var potentials []body.Potential
+	for _, s := range strings.Split(spec, ";") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		kind, params, err := parseSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		p, err := newPotential(kind, params)
+		if err != nil {
+			return nil, err
+		}
+		potentials = append(potentials, p)
+	}
+	return potentials, nil
+}
+
+// Split a spec of the form kind,key=value,... into its kind and parameters
+func parseSpec(s string) (string, map[string]float64, error) {
+	fields := strings.Split(strings.TrimSpace(s), ",")
+	kind := fields[0]
+	params := make(map[string]float64)
+	for _, f := range fields[1:] {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return kind, nil, fmt.Errorf("%v: expected key=value, got %q", kind, f)
+		}
+		v, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return kind, nil, fmt.Errorf("%v: bad value for %v: %v", kind, kv[0], err)
+		}
+		params[kv[0]] = v
+	}
+	return kind, params, nil
+}
+
+// Check the kind is one of allowed and takes every given parameter
+func checkSpec(what, kind string, params map[string]float64, allowed map[string][]string) error {
+	keys, ok := allowed[kind]
+	if !ok {
+		var kinds []string
+		for k := range allowed {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		return fmt.Errorf("unknown %v %q, expected one of %v", what, kind, strings.Join(kinds, ", "))
+	}
+	for k := range params {
+		found := false
+		for _, a := range keys {
+			found = found || a == k
+		}
+		if !found {
+			return fmt.Errorf("%v %v does not take parameter %v", what, kind, k)
+		}
+	}
+	return nil
+}
+
+func newPotential(kind string, params map[string]float64) (body.Potential, error) {
+	allowed := map[string][]string{
+		"uniform": {"gx", "gy", "gz"},
+		"point":   {"m", "x", "y", "z"},
+		"nfw":     {"m", "rs", "x", "y", "z"},
+		"log":     {"v0", "rc", "x", "y", "z"},
+	}
+	if err := checkSpec("potential", kind, params, allowed); err != nil {
+		return nil, err
+	}
+	center := vector.Vector{params["x"], params["y"], params["z"]}
+	switch kind {
+	case "uniform":
+		return body.UniformField{Field: vector.Vector{params["gx"], params["gy"], params["gz"]}}, nil
+	case "point":
+		return body.PointPotential{Center: center, Mass: params["m"]}, nil
+	case "nfw":
+		if params["rs"] <= 0 {
+			return nil, fmt.Errorf("potential nfw requires rs > 0")
+		}
+		return body.NFWHalo{Center: center, Mass: params["m"], ScaleRadius: params["rs"]}, nil
+	default:
+		if params["rc"] <= 0 {
+			return nil, fmt.Errorf("potential log requires rc > 0")
+		}
+		return body.LogarithmicHalo{Center: center, V0: params["v0"], CoreRadius: params["rc"]}, nil
+	}
+}
+
+// Spec of a potential that parsePotentials turns back into it
+func potentialSpec(p body.Potential) (string, error) {
+	switch p := p.(type) {
+	case body.UniformField:
+		return fmt.Sprintf("uniform,gx=%v,gy=%v,gz=%v", p.Field.X, p.Field.Y, p.Field.Z), nil
+	case body.PointPotential:
+		return fmt.Sprintf("point,m=%v,x=%v,y=%v,z=%v", p.Mass, p.Center.X, p.Center.Y, p.Center.Z), nil
+	case body.NFWHalo:
+		return fmt.Sprintf("nfw,m=%v,rs=%v,x=%v,y=%v,z=%v", p.Mass, p.ScaleRadius, p.Center.X, p.Center.Y,
+			p.Center.Z), nil
+	case body.LogarithmicHalo:
+		return fmt.Sprintf("log,v0=%v,rc=%v,x=%v,y=%v,z=%v", p.V0, p.CoreRadius, p.Center.X, p.Center.Y,
+			p.Center.Z), nil
+	}
+	return "", fmt.Errorf("no spec for potential %v", p)
+}
+
+func (w World) screenToWorld(screen, offset vector.Vector) vector.Vector {
+	f := w.mpp / (w.scale * w.mag)
+	x, y := (screen.X-offset.X)*f, (screen.Y-offset.Y)*f
+	if w.frameAngle != 0 {
+		c, s := math.Cos(w.frameAngle), math.Sin(w.frameAngle)
+		dx, dy := x-w.frameCenter.X, y-w.frameCenter.Y
+		x, y = w.frameCenter.X+c*dx-s*dy, w.frameCenter.Y+s*dx+c*dy
+	}
+	return vector.Vector{x, y, 0}
+}
+
+// Sum of the external potentials at a world position
+func (w World) externalPotential(pos vector.Vector) float64 {
+	e := 0.0
+	for _, p := range w.potentials {
+		e += p.Energy(pos)
+	}
+	return e
+}
+
+// Draw iso-potential contours of the external potentials over the visible
+// part of the world using marching squares on a coarse screen grid.
+func (w World) drawContours(imd *imdraw.IMDraw, bounds pixel.Rect, offset vector.Vector) {
+	if len(w.potentials) == 0 {
+		return
+	}
+	nx := int(bounds.W()/contourCell) + 1
+	ny := int(bounds.H()/contourCell) + 1
+	grid := make([][]float64, nx)
+	var values []float64
+	for i := range grid {
+		grid[i] = make([]float64, ny)
+		for j := range grid[i] {
+			screen := vector.Vector{bounds.Min.X + float64(i)*contourCell, bounds.Min.Y + float64(j)*contourCell, 0}
+			grid[i][j] = w.externalPotential(w.screenToWorld(screen, offset))
+			values = append(values, grid[i][j])
+		}
+	}
+	// Spread the levels over the bulk of the values so a singular center
+	// does not squeeze every contour into a few pixels
+	sort.Float64s(values)
+	lo := values[len(values)/20]
+	hi := values[len(values)-1-len(values)/20]
+	if hi <= lo {
+		return
+	}
+	imd.Color = colornames.Steelblue
+	for l := 1; l <= contourLevels; l++ {
+		level := lo + (hi-lo)*float64(l)/float64(contourLevels+1)
+		for i := 0; i < nx-1; i++ {
+			for j := 0; j < ny-1; j++ {
+				x := bounds.Min.X + float64(i)*contourCell
+				y := bounds.Min.Y + float64(j)*contourCell
+				corners := [4]pixel.Vec{pixel.V(x, y), pixel.V(x+contourCell, y),
+					pixel.V(x+contourCell, y+contourCell), pixel.V(x, y+contourCell)}
+				v := [4]float64{grid[i][j], grid[i+1][j], grid[i+1][j+1], grid[i][j+1]}
+				var crossings []pixel.Vec
+				for k := 0; k < 4; k++ {
+					a, b := v[k], v[(k+1)%4]
+					if (a < level) != (b < level) {
+						t := (level - a) / (b - a)
+						crossings = append(crossings, pixel.Lerp(corners[k], corners[(k+1)%4], t))
+					}
+				}
+				for k := 0; k+1 < len(crossings); k += 2 {
+					imd.Push(crossings[k], crossings[k+1])
+					imd.Line(1)
+				}
+			}
+		}
+	}
+}
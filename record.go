@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/nbody-go/body"
+	"github.com/seifertd/nbody-go/trajectory"
+)
+
+// Records the bodies of the world to a trajectory file every so many
+// simulated seconds, with an event for every body that merges or escapes
+type recorder struct {
+	out   *trajectory.Writer
+	every float64
+	next  float64
+	// reused for every frame
+	states []trajectory.State
+}
+
+// Start recording the world to path, with coordinates of floatSize bytes
+func newRecorder(w *World, path string, every float64, floatSize int) (*recorder, error) {
+	out, err := trajectory.Create(path, trajectory.Header{FloatSize: floatSize, Every: every, Seed: w.seed,
+		Attributes: map[string]float64{
+			"width": float64(w.width), "height": float64(w.height), "scale": w.scale, "meters_per_pixel": w.mpp,
+			"mag": w.mag,
+		}})
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range w.bodies {
+		if err := out.Add(w.elapsed, b.Id, b.Name, b.Mass, b.Radius, b.TestParticle); err != nil {
+			out.Close()
+			return nil, err
+		}
+	}
+	r := &recorder{out: out, every: every, next: w.elapsed}
+	return r, r.sample(w)
+}
+
+// Record a frame if one is due
+func (r *recorder) sample(w *World) error {
+	if w.elapsed < r.next {
+		return nil
+	}
+	r.next = w.elapsed + r.every
+	r.states = r.states[:0]
+	for _, b := range w.bodies {
+		r.states = append(r.states, trajectory.State{Id: b.Id, Mass: b.Mass, Radius: b.Radius, Pos: b.Pos, Vel: b.Vel})
+	}
+	return r.out.Frame(w.elapsed, r.states)
+}
+
+// Record b leaving the world, merged into the body with id into or escaped
+func (r *recorder) removed(w *World, b *body.Body, kind trajectory.EventKind, into uint64) error {
+	return r.out.Remove(w.elapsed, b.Id, kind, into)
+}
+
+// Stop recording after a write failed
+func (w *World) recordError(err error) {
+	fmt.Printf("Unable to record trajectory, stopping: %v\n", err)
+	w.recorder.out.Close()
+	w.recorder = nil
+}
+
+// Write the rest of the recording and close its file
+func (w *World) closeRecording(path string) {
+	if w.recorder == nil {
+		return
+	}
+	if err := w.recorder.out.Close(); err != nil {
+		fmt.Printf("Unable to record trajectory: %v\n", err)
+		return
+	}
+	fmt.Printf("Recorded the trajectory to %v\n", path)
+}
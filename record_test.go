@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/seifertd/nbody-go/trajectory"
+	"io"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	resetSprites()
+	rng := newRand(7)
+	world := randomWorld(1024, 1024, 40, 0.5, 0.3, orbitSpread{0.2, 0.1}, rng)
+	world.seed, world.spt = 7, 20
+	path := t.TempDir() + "/run.trj"
+	var err error
+	if world.recorder, err = newRecorder(world, path, 5*world.timestep(), 8); err != nil {
+		t.Fatal(err)
+	}
+	start := len(world.bodies)
+	for i := 0; i < 20; i++ {
+		world.tick()
+	}
+	world.closeRecording(path)
+
+	r, err := trajectory.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if h := r.Header(); h.Seed != 7 || h.Attributes["meters_per_pixel"] != world.mpp {
+		t.Errorf("wrong header %+v", h)
+	}
+	var last trajectory.Frame
+	frames := 0
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = f
+		frames++
+	}
+	// one frame at the start and one every 5 of the 400 steps
+	if frames != 81 {
+		t.Errorf("recorded %v frames, expected 81", frames)
+	}
+	if len(last.Bodies) != len(world.bodies) {
+		t.Fatalf("last frame has %v bodies, the world %v", len(last.Bodies), len(world.bodies))
+	}
+	for i, b := range world.bodies {
+		if s := last.Bodies[i]; s.Id != b.Id || s.Pos != b.Pos || s.Vel != b.Vel || s.Mass != b.Mass {
+			t.Errorf("body %v recorded as %+v, is %v", i, s, b)
+		}
+	}
+	added, merged, escaped := 0, 0, 0
+	for _, e := range r.Events() {
+		switch e.Kind {
+		case trajectory.Added:
+			added++
+		case trajectory.Merged:
+			merged++
+		case trajectory.Escaped:
+			escaped++
+		}
+	}
+	if added != start || merged != len(world.mergers) || start-merged-escaped != len(world.bodies) {
+		t.Errorf("events: %v added, %v merged, %v escaped; %v bodies at the start, %v mergers, %v at the end",
+			added, merged, escaped, start, len(world.mergers), len(world.bodies))
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/imdraw"
+	"github.com/seifertd/nbody-go/body"
+	"github.com/seifertd/nbody-go/trajectory"
+	"golang.org/x/image/colornames"
+	"io"
+	"math"
+	"sort"
+)
+
+// Frames decoded around the one shown, so playing either way only reads the
+// file every so often
+const replayWindow = 256
+
+// Height in pixels of the timeline along the bottom of the window
+const timelineHeight = 12
+
+// Plays a recorded trajectory back into a world, world.spt frames per UI
+// tick while the world is running
+type player struct {
+	r          *trajectory.Reader
+	start, end float64
+	// simulated seconds between frames
+	every float64
+	// playing backwards
+	reverse bool
+	frames  []trajectory.Frame
+	at      int
+	// bodies by id, kept between frames so the inspector and follow stay
+	// on them
+	bodies map[uint64]*body.Body
+}
+
+func newPlayer(r *trajectory.Reader) (*player, error) {
+	start, end, err := r.Span()
+	if err != nil {
+		return nil, err
+	}
+	p := &player{r: r, start: start, end: end, every: r.Header().Every, bodies: map[uint64]*body.Body{}}
+	if err := p.load(start, false); err != nil {
+		return nil, err
+	}
+	if len(p.frames) == 0 {
+		return nil, fmt.Errorf("no frames recorded")
+	}
+	if p.every <= 0 && len(p.frames) > 1 {
+		p.every = p.frames[1].Time - p.frames[0].Time
+	}
+	return p, nil
+}
+
+// The world a recording was made in, showing its first frame
+func replayWorld(p *player, mag float64) *World {
+	attr := p.r.Header().Attributes
+	w := &World{
+		width: int(attr["width"]), height: int(attr["height"]), scale: attr["scale"], mpp: attr["meters_per_pixel"],
+		mag: mag, spt: 1, dt: p.every, running: true, seed: p.r.Header().Seed,
+	}
+	if w.width == 0 || w.height == 0 {
+		w.width, w.height = 1024, 1024
+	}
+	if w.scale == 0 {
+		w.scale = 1
+	}
+	p.show(w)
+	if w.mpp == 0 {
+		w.mpp = 1
+		w.fitToScreen()
+	}
+	return w
+}
+
+// Decode the frames around t into the window, ending at t when going
+// backwards, and show the frame at t
+func (p *player) load(t float64, backward bool) error {
+	from := t
+	if backward {
+		from = t - float64(replayWindow-1)*p.every
+	}
+	if err := p.r.Seek(from); err != nil {
+		return err
+	}
+	p.frames = p.frames[:0]
+	for len(p.frames) < replayWindow {
+		f, err := p.r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		p.frames = append(p.frames, f)
+	}
+	p.at = sort.Search(len(p.frames), func(i int) bool { return p.frames[i].Time >= t })
+	if p.at == len(p.frames) && p.at > 0 {
+		p.at--
+	}
+	return nil
+}
+
+// Jump to the frame at t
+func (p *player) seek(w *World, t float64) error {
+	t = math.Max(p.start, math.Min(p.end, t))
+	if err := p.load(t, p.reverse); err != nil {
+		return err
+	}
+	p.show(w)
+	return nil
+}
+
+// Move world.spt frames on, in the direction of play, stopping at either
+// end of the recording
+func (p *player) step(w *World) error {
+	if !w.running || len(p.frames) == 0 {
+		return nil
+	}
+	n := w.spt
+	if p.reverse {
+		n = -n
+	}
+	if i := p.at + n; i >= 0 && i < len(p.frames) {
+		p.at = i
+		p.show(w)
+		return nil
+	}
+	t := p.frames[p.at].Time + float64(n)*p.every
+	if t >= p.end || t <= p.start {
+		w.running = false
+	}
+	return p.seek(w, t)
+}
+
+// Put the bodies of the current frame in the world
+func (p *player) show(w *World) {
+	if len(p.frames) == 0 {
+		return
+	}
+	f := p.frames[p.at]
+	w.elapsed = f.Time
+	w.bodies = w.bodies[:0]
+	for _, s := range f.Bodies {
+		b, ok := p.bodies[s.Id]
+		if !ok {
+			b = p.newBody(s)
+			p.bodies[s.Id] = b
+		}
+		b.Pos, b.Vel, b.Mass, b.Radius = s.Pos, s.Vel, s.Mass, s.Radius
+		w.bodies = append(w.bodies, b)
+	}
+}
+
+// A body for the recorded state s, drawn with the sprite of its name when
+// there is one
+func (p *player) newBody(s trajectory.State) *body.Body {
+	name := fmt.Sprintf("#%v", s.Id)
+	testParticle := false
+	if e, ok := p.r.Body(s.Id); ok {
+		name, testParticle = e.Name, e.TestParticle
+	}
+	sprite, _ := solarSprite(name)
+	if sprite == nil {
+		sprite = sprites["circle"]
+	}
+	b := body.NewBodyVector(name, s.Pos, s.Vel, s.Radius, s.Mass, sprite)
+	b.Id, b.TestParticle = s.Id, testParticle
+	return b
+}
+
+// Fraction of the recording played
+func (p *player) progress(w *World) float64 {
+	if p.end <= p.start {
+		return 1
+	}
+	return (w.elapsed - p.start) / (p.end - p.start)
+}
+
+// Draw the timeline along the bottom of bounds
+func (p *player) drawTimeline(imd *imdraw.IMDraw, w *World, bounds pixel.Rect) {
+	h := timelineHeight * w.mag
+	imd.Color = colornames.Dimgray
+	imd.Push(pixel.V(bounds.Min.X, bounds.Min.Y), pixel.V(bounds.Max.X, bounds.Min.Y+h))
+	imd.Rectangle(0)
+	imd.Color = colornames.Lightgray
+	imd.Push(pixel.V(bounds.Min.X, bounds.Min.Y), pixel.V(bounds.Min.X+p.progress(w)*bounds.W(), bounds.Min.Y+h))
+	imd.Rectangle(0)
+}
+
+// Seek to the time under a click at pos on the timeline, false when pos is
+// not on it
+func (p *player) click(w *World, bounds pixel.Rect, pos pixel.Vec) (bool, error) {
+	if pos.Y > bounds.Min.Y+timelineHeight*w.mag {
+		return false, nil
+	}
+	return true, p.seek(w, p.start+(pos.X-bounds.Min.X)/bounds.W()*(p.end-p.start))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/seifertd/nbody-go/trajectory"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	resetSprites()
+	world := randomWorld(1024, 1024, 40, 0.5, 0.3, orbitSpread{0.2, 0.1}, newRand(7))
+	world.spt = 20
+	path := t.TempDir() + "/run.trj"
+	var err error
+	if world.recorder, err = newRecorder(world, path, world.timestep(), 8); err != nil {
+		t.Fatal(err)
+	}
+	// 601 frames, more than fit in the player's window
+	for i := 0; i < 30; i++ {
+		world.tick()
+	}
+	world.closeRecording(path)
+
+	r, err := trajectory.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	p, err := newPlayer(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := replayWorld(p, 1)
+	if replayed.elapsed != 0 || replayed.mpp != world.mpp || len(replayed.bodies) != 41 {
+		t.Fatalf("replay starts at %v with %v bodies, %v m/px", replayed.elapsed, len(replayed.bodies), replayed.mpp)
+	}
+	first := replayed.bodies[5]
+	replayed.spt = 7
+	for replayed.running {
+		if err := p.step(replayed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if replayed.elapsed != world.elapsed || len(replayed.bodies) != len(world.bodies) {
+		t.Fatalf("replay ended at %v with %v bodies, run at %v with %v", replayed.elapsed, len(replayed.bodies),
+			world.elapsed, len(world.bodies))
+	}
+	for i, b := range world.bodies {
+		if r := replayed.bodies[i]; r.Id != b.Id || r.Pos != b.Pos || r.Vel != b.Vel {
+			t.Fatalf("body %v replayed as %v, is %v", i, r, b)
+		}
+	}
+
+	p.reverse, replayed.running = true, true
+	for replayed.running {
+		if err := p.step(replayed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if replayed.elapsed != 0 || replayed.bodies[5] != first {
+		t.Errorf("reversed replay ended at %v, body 5 %v instead of %v", replayed.elapsed, replayed.bodies[5], first)
+	}
+	if err := p.seek(replayed, 299.5); err != nil || replayed.elapsed != 300 {
+		t.Errorf("seek to 299.5 gave %v: %v", replayed.elapsed, err)
+	}
+	if err := p.seek(replayed, 1e9); err != nil || replayed.elapsed != world.elapsed {
+		t.Errorf("seek past the end gave %v: %v", replayed.elapsed, err)
+	}
+}
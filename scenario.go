@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"github.com/faiface/pixel"
+	"github.com/seifertd/nbody-go/scenario"
+	math_rand "math/rand"
+	"path/filepath"
+	"strings"
+)
+
+// Build a world from a scenario file. Sprite paths in the scenario are
+// relative to the file.
+func loadScenarioWorld(path string, w, h int, rng *math_rand.Rand) (*World, error) {
+	s, err := scenario.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Loaded scenario %v from %v\n", s.Name, path)
+	world, err := worldFromScenario(s, filepath.Dir(path), w, h, rng)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return world, nil
+}
+
+func worldFromScenario(s *scenario.Scenario, dir string, w, h int, rng *math_rand.Rand) (*World, error) {
+	world := &World{
+		scale:            1.0,
+		spt:              1,
+		running:          true,
+		elapsed:          0,
+		width:            w,
+		height:           h,
+		mag:              1.0,
+		dt:               s.TimestepSeconds(),
+		followBarycenter: s.View.FollowBarycenter,
+	}
+	if s.View.Scale > 0 {
+		world.scale = s.View.Scale
+	}
+	if s.View.StepsPerTick > 0 {
+		world.spt = s.View.StepsPerTick
+	}
+
+	var err error
+	world.potentials, err = parsePotentials(strings.Join(s.Potentials, ";"))
+	if err != nil {
+		return nil, fmt.Errorf("potentials: %v", err)
+	}
+	charge := 0.0
+	if s.Law != "" {
+		world.law, charge, err = parseForceLaw(s.Law)
+		if err != nil {
+			return nil, fmt.Errorf("law: %v", err)
+		}
+	}
+
+	for name, path := range s.Sprites {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		pic, err := loadPicture(path)
+		if err != nil {
+			return nil, fmt.Errorf("sprites.%v: %v", name, err)
+		}
+		if sprites == nil {
+			sprites = make(map[string]*pixel.Sprite)
+		}
+		sprites[name] = pixel.NewSprite(pic, pic.Bounds())
+	}
+
+	world.bodies = s.Build()
+	for i, b := range world.bodies {
+		name := s.Bodies[i].Sprite
+		if name == "" {
+			b.Sprite = randomPlanetSprite(rng)
+		} else if sprite, ok := sprites[name]; ok {
+			b.Sprite = sprite
+		} else {
+			return nil, fmt.Errorf("bodies[%d] (%v).sprite: unknown sprite %q", i, b.Name, name)
+		}
+		fmt.Printf("%v\n", b)
+	}
+	if charge != 0 {
+		assignCharges(world, charge, rng)
+	}
+
+	// Without a view scale fit the whole system on the screen
+	world.mpp = s.ViewMetersPerPixel()
+	if world.mpp == 0 {
+		world.fitToScreen()
+	}
+	return world, nil
+}
@@ -0,0 +1,288 @@
+// Package scenario reads initial conditions for a simulation from JSON
+// files, so systems can be kept and versioned as data instead of code.
+// See docs/scenario.md for the format.
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Conversion factors to SI for the unit names allowed in a scenario
+var (
+	LengthUnits = map[string]float64{
+		"m":   1,
+		"km":  1e3,
+		"au":  1.495978707e11,
+		"ly":  9.4607304725808e15,
+		"pc":  3.0856775814913673e16,
+		"kpc": 3.0856775814913673e19,
+	}
+	MassUnits = map[string]float64{
+		"kg":     1,
+		"mearth": 5.9722e24,
+		"mjup":   1.89813e27,
+		"msun":   1.98847e30,
+	}
+	TimeUnits = map[string]float64{
+		"s":    1,
+		"min":  60,
+		"hour": 3600,
+		"day":  86400,
+		"yr":   3.15576e7,
+	}
+)
+
+// Units every length, mass and time in the scenario is given in. Empty
+// names mean meters, kilograms and seconds.
+type Units struct {
+	Length string `json:"length,omitempty"`
+	Mass   string `json:"mass,omitempty"`
+	Time   string `json:"time,omitempty"`
+}
+
+func (u Units) factors() (float64, float64, float64) {
+	l, m, t := 1.0, 1.0, 1.0
+	if u.Length != "" {
+		l = LengthUnits[u.Length]
+	}
+	if u.Mass != "" {
+		m = MassUnits[u.Mass]
+	}
+	if u.Time != "" {
+		t = TimeUnits[u.Time]
+	}
+	return l, m, t
+}
+
+// View settings for the viewer. MetersPerPixel is in the length unit.
+type View struct {
+	Scale            float64 `json:"scale,omitempty"`
+	MetersPerPixel   float64 `json:"meters_per_pixel,omitempty"`
+	StepsPerTick     int     `json:"steps_per_tick,omitempty"`
+	FollowBarycenter bool    `json:"follow_barycenter,omitempty"`
+}
+
+// Body initial conditions. Position and Velocity have 2 or 3 components.
+// Sprite names a built in image or an entry in the scenario's sprites, an
+// empty sprite picks a random planet.
+type Body struct {
+	Name         string    `json:"name"`
+	Position     []float64 `json:"position"`
+	Velocity     []float64 `json:"velocity"`
+	Mass         float64   `json:"mass"`
+	Radius       float64   `json:"radius"`
+	Charge       float64   `json:"charge,omitempty"`
+	SpinPeriod   float64   `json:"spin_period,omitempty"`
+	TestParticle bool      `json:"test_particle,omitempty"`
+	Sprite       string    `json:"sprite,omitempty"`
+}
+
+// Scenario is a complete set of initial conditions. Law and Potentials use
+// the same specs as the --law and --potential command line options.
+type Scenario struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Units       Units             `json:"units"`
+	Integrator  string            `json:"integrator,omitempty"`
+	Timestep    float64           `json:"timestep,omitempty"`
+	Law         string            `json:"law,omitempty"`
+	Potentials  []string          `json:"potentials,omitempty"`
+	View        View              `json:"view"`
+	Sprites     map[string]string `json:"sprites,omitempty"`
+	Bodies      []Body            `json:"bodies"`
+}
+
+// Integrators a scenario may ask for
+var Integrators = []string{"rk4"}
+
+// ValidationError lists every problem found in a scenario
+type ValidationError struct {
+	Problems []string
+}
+
+func (v *ValidationError) Error() string {
+	return "invalid scenario:\n  " + strings.Join(v.Problems, "\n  ")
+}
+
+func (v *ValidationError) add(format string, args ...interface{}) {
+	v.Problems = append(v.Problems, fmt.Sprintf(format, args...))
+}
+
+// Read and validate a scenario. Unknown fields are an error so typos do
+// not silently fall back to defaults.
+func Read(r io.Reader) (*Scenario, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	s := &Scenario{}
+	if err := dec.Decode(s); err != nil {
+		var offset int64 = -1
+		if serr, ok := err.(*json.SyntaxError); ok {
+			offset = serr.Offset
+		} else if terr, ok := err.(*json.UnmarshalTypeError); ok {
+			offset = terr.Offset
+		}
+		if offset >= 0 {
+			line := 1 + bytes.Count(data[:offset], []byte("\n"))
+			return nil, fmt.Errorf("invalid scenario: line %d: %v", line, err)
+		}
+		return nil, fmt.Errorf("invalid scenario: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func Load(path string) (*Scenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	s, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return s, nil
+}
+
+func unitNames(units map[string]float64) string {
+	var names []string
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Validate checks the scenario, returning a *ValidationError listing every
+// problem found.
+func (s *Scenario) Validate() error {
+	v := &ValidationError{}
+	if _, ok := LengthUnits[s.Units.Length]; s.Units.Length != "" && !ok {
+		v.add("units.length: unknown unit %q, expected one of %v", s.Units.Length, unitNames(LengthUnits))
+	}
+	if _, ok := MassUnits[s.Units.Mass]; s.Units.Mass != "" && !ok {
+		v.add("units.mass: unknown unit %q, expected one of %v", s.Units.Mass, unitNames(MassUnits))
+	}
+	if _, ok := TimeUnits[s.Units.Time]; s.Units.Time != "" && !ok {
+		v.add("units.time: unknown unit %q, expected one of %v", s.Units.Time, unitNames(TimeUnits))
+	}
+	if s.Integrator != "" {
+		found := false
+		for _, i := range Integrators {
+			found = found || i == s.Integrator
+		}
+		if !found {
+			v.add("integrator: unknown integrator %q, expected one of %v", s.Integrator, strings.Join(Integrators, ", "))
+		}
+	}
+	if s.Timestep < 0 {
+		v.add("timestep: must be positive, got %v", s.Timestep)
+	}
+	if s.View.Scale < 0 {
+		v.add("view.scale: must be positive, got %v", s.View.Scale)
+	}
+	if s.View.MetersPerPixel < 0 {
+		v.add("view.meters_per_pixel: must be positive, got %v", s.View.MetersPerPixel)
+	}
+	if s.View.StepsPerTick < 0 {
+		v.add("view.steps_per_tick: must be positive, got %v", s.View.StepsPerTick)
+	}
+	if len(s.Bodies) == 0 {
+		v.add("bodies: at least one body is required")
+	}
+	names := make(map[string]int)
+	for i, b := range s.Bodies {
+		where := fmt.Sprintf("bodies[%d]", i)
+		if b.Name == "" {
+			v.add("%v.name: is required", where)
+		} else if j, ok := names[b.Name]; ok {
+			v.add("%v.name: %q is already used by bodies[%d]", where, b.Name, j)
+		} else {
+			names[b.Name] = i
+			where = fmt.Sprintf("bodies[%d] (%v)", i, b.Name)
+		}
+		if len(b.Position) != 2 && len(b.Position) != 3 {
+			v.add("%v.position: needs 2 or 3 components, got %d", where, len(b.Position))
+		}
+		if len(b.Velocity) != 2 && len(b.Velocity) != 3 {
+			v.add("%v.velocity: needs 2 or 3 components, got %d", where, len(b.Velocity))
+		}
+		if b.TestParticle && b.Mass != 0 {
+			v.add("%v.mass: test particles are massless, got %v", where, b.Mass)
+		} else if !b.TestParticle && b.Mass <= 0 {
+			v.add("%v.mass: must be positive, got %v", where, b.Mass)
+		}
+		if b.Radius <= 0 {
+			v.add("%v.radius: must be positive, got %v", where, b.Radius)
+		}
+		for _, x := range append(append([]float64{b.Mass, b.Radius, b.Charge, b.SpinPeriod}, b.Position...), b.Velocity...) {
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				v.add("%v: values must be finite numbers", where)
+				break
+			}
+		}
+	}
+	if len(v.Problems) > 0 {
+		return v
+	}
+	return nil
+}
+
+func toVector(c []float64, f float64) vector.Vector {
+	v := vector.Vector{c[0] * f, c[1] * f, 0}
+	if len(c) == 3 {
+		v.Z = c[2] * f
+	}
+	return v
+}
+
+// Seconds per integration step, 1 when not given
+func (s *Scenario) TimestepSeconds() float64 {
+	if s.Timestep == 0 {
+		return 1
+	}
+	_, _, t := s.Units.factors()
+	return s.Timestep * t
+}
+
+// Meters per pixel of the view, 0 when not given
+func (s *Scenario) ViewMetersPerPixel() float64 {
+	l, _, _ := s.Units.factors()
+	return s.View.MetersPerPixel * l
+}
+
+// Build the bodies in SI units, in the same order as s.Bodies. Sprites are
+// left for the caller to fill in.
+func (s *Scenario) Build() []*body.Body {
+	l, m, t := s.Units.factors()
+	bodies := make([]*body.Body, len(s.Bodies))
+	for i, b := range s.Bodies {
+		pos := toVector(b.Position, l)
+		vel := toVector(b.Velocity, l/t)
+		if b.TestParticle {
+			bodies[i] = body.NewTestParticle(b.Name, pos, vel, b.Radius*l, nil)
+		} else {
+			bodies[i] = body.NewBodyVector(b.Name, pos, vel, b.Radius*l, b.Mass*m, nil)
+		}
+		bodies[i].Charge = b.Charge
+		if b.SpinPeriod != 0 {
+			bodies[i].SetSpinPeriod(b.SpinPeriod * t)
+		}
+	}
+	return bodies
+}
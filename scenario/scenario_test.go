@@ -0,0 +1,88 @@
+package scenario
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExampleScenariosAreValid(t *testing.T) {
+	paths, err := filepath.Glob("../scenarios/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no example scenarios found: %v", err)
+	}
+	for _, path := range paths {
+		if _, err := Load(path); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+}
+
+func TestUnitConversion(t *testing.T) {
+	s, err := Read(strings.NewReader(`{
+		"name": "units",
+		"units": {"length": "au", "mass": "msun", "time": "day"},
+		"timestep": 0.5,
+		"view": {"meters_per_pixel": 0.01},
+		"bodies": [
+			{"name": "a", "position": [1, 0, 2], "velocity": [0, 1], "mass": 1, "radius": 0.001, "spin_period": 1}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := s.Build()[0]
+	au := LengthUnits["au"]
+	if b.Pos.X != au || b.Pos.Z != 2*au || b.Vel.Y != au/86400 || b.Mass != MassUnits["msun"] {
+		t.Errorf("body not converted to SI: %v", b)
+	}
+	if math.Abs(b.SpinPeriod()-86400) > 1e-6 {
+		t.Errorf("spin period not converted to seconds: %v", b.SpinPeriod())
+	}
+	if s.TimestepSeconds() != 43200 || s.ViewMetersPerPixel() != 0.01*au {
+		t.Errorf("timestep or view not converted: %v %v", s.TimestepSeconds(), s.ViewMetersPerPixel())
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	_, err := Read(strings.NewReader(`{
+		"name": "broken",
+		"units": {"length": "furlong"},
+		"integrator": "euler",
+		"bodies": [
+			{"name": "a", "position": [0], "velocity": [0, 0], "mass": -1, "radius": 1},
+			{"name": "a", "position": [0, 0], "velocity": [0, 0], "mass": 1, "radius": 0},
+			{"name": "t", "position": [0, 0], "velocity": [0, 0], "mass": 1, "radius": 1, "test_particle": true}
+		]
+	}`))
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	expected := []string{
+		"units.length: unknown unit \"furlong\"",
+		"integrator: unknown integrator \"euler\"",
+		"bodies[0] (a).position: needs 2 or 3 components",
+		"bodies[0] (a).mass: must be positive",
+		"bodies[1].name: \"a\" is already used by bodies[0]",
+		"bodies[1].radius: must be positive",
+		"bodies[2] (t).mass: test particles are massless",
+	}
+	for _, e := range expected {
+		if !strings.Contains(verr.Error(), e) {
+			t.Errorf("error should contain %q:\n%v", e, verr)
+		}
+	}
+}
+
+func TestSyntaxErrorsHaveLines(t *testing.T) {
+	_, err := Read(strings.NewReader("{\n  \"name\": \"x\",\n  \"bodies\": [,]\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("syntax error should give the line: %v", err)
+	}
+	_, err = Read(strings.NewReader(`{"name": "x", "bodys": []}`))
+	if err == nil || !strings.Contains(err.Error(), "bodys") {
+		t.Errorf("unknown fields should be rejected: %v", err)
+	}
+}
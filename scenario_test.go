@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestLoadScenarioWorld(t *testing.T) {
+	resetSprites("sun", "mercury", "venus", "earth", "luna", "mars")
+	world, err := loadScenarioWorld("scenarios/inner-solar.json", 1024, 1024, newRand(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(world.bodies) != 6 || world.dt != 60 || world.spt != 10 || world.mpp != 5.5e8 {
+		t.Errorf("world not set up from scenario: %v bodies dt=%v spt=%v mpp=%v",
+			len(world.bodies), world.dt, world.spt, world.mpp)
+	}
+	if earth := world.bodies[4]; earth.Name != "Earth" || earth.Pos.X != -147.09e9 || earth.Vel.Y != -30.29e3 {
+		t.Errorf("earth not converted to SI: %v", earth)
+	}
+
+	delete(sprites, "luna")
+	if _, err := loadScenarioWorld("scenarios/inner-solar.json", 1024, 1024, newRand(1)); err == nil {
+		t.Errorf("unknown sprites should be an error")
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"github.com/faiface/pixel"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	math_rand "math/rand"
+	"strings"
+)
+
+const (
+	au = 1.495978707e11
+	km = 1e3
+	// Julian date of the J2000 epoch
+	j2000 = 2451545.0
+)
+
+// A body of the solar system with its orbit around its parent at J2000.
+// Angles are in degrees referred to the ecliptic, spin is the sidereal
+// rotation period in hours, negative for retrograde, 0 when tidally locked.
+type solarBody struct {
+	name   string
+	parent string
+	group  string
+	mass   float64
+	radius float64
+	a      float64
+	e      float64
+	i      float64
+	node   float64
+	peri   float64
+	m      float64
+	spin   float64
+}
+
+// Planet and Pluto elements are the J2000 values of Standish's
+// "Keplerian Elements for Approximate Positions of the Major Planets" and
+// are for the barycenter of each planet and its moons. Moon and asteroid
+// elements are approximate, the moon phases only roughly those at J2000.
+var solarBodies = []solarBody{
+	{"Sol", "", "", 1.98847e30, 695_700 * km, 0, 0, 0, 0, 0, 0, 609.12},
+	{"Mercury", "Sol", "inner", 3.3011e23, 2_439.7 * km, 0.38709927 * au, 0.20563593, 7.00497902, 48.33076593, 29.12703035, 174.79252722, 1407.6},
+	{"Venus", "Sol", "inner", 4.8675e24, 6_051.8 * km, 0.72333566 * au, 0.00677672, 3.39467605, 76.67984255, 54.92262463, 50.37663232, -5832.6},
+	{"Earth", "Sol", "inner", 5.9722e24, 6_371.0 * km, 1.00000261 * au, 0.01671123, -0.00001531, 0, 102.93768193, 357.52688973, 23.9345},
+	{"Luna", "Earth", "moons", 7.346e22, 1_737.4 * km, 384_400 * km, 0.0549, 5.145, 125.08, 318.15, 135.27, 0},
+	{"Mars", "Sol", "inner", 6.4171e23, 3_389.5 * km, 1.52371034 * au, 0.09339410, 1.84969142, 49.55953891, 286.4968315, 19.39019754, 24.6229},
+	{"Phobos", "Mars", "moons", 1.0659e16, 11.267 * km, 9_376 * km, 0.0151, 26.04, 82.9, 150.06, 91.06, 0},
+	{"Deimos", "Mars", "moons", 1.4762e15, 6.2 * km, 23_463 * km, 0.00033, 27.58, 83.5, 260.73, 325.33, 0},
+	{"Jupiter", "Sol", "outer", 1.89813e27, 69_911 * km, 5.20288700 * au, 0.04838624, 1.30439695, 100.47390909, 274.25457074, 19.66796068, 9.925},
+	{"Io", "Jupiter", "moons", 8.931938e22, 1_821.6 * km, 421_700 * km, 0.0041, 2.21, 337.0, 84.13, 342.02, 0},
+	{"Europa", "Jupiter", "moons", 4.799844e22, 1_560.8 * km, 671_034 * km, 0.009, 1.79, 336.8, 88.97, 171.02, 0},
+	{"Ganymede", "Jupiter", "moons", 1.4819e23, 2_634.1 * km, 1_070_412 * km, 0.0013, 2.21, 340.3, 192.42, 317.54, 0},
+	{"Callisto", "Jupiter", "moons", 1.075938e23, 2_410.3 * km, 1_882_709 * km, 0.0074, 2.02, 337.9, 52.64, 181.41, 0},
+	{"Saturn", "Sol", "outer", 5.6834e26, 58_232 * km, 9.53667594 * au, 0.05386179, 2.48599187, 113.66242448, 338.93645383, 317.35536592, 10.656},
+	{"Rhea", "Saturn", "moons", 2.306e21, 763.8 * km, 527_108 * km, 0.001, 28.06, 169.5, 241.62, 179.78, 0},
+	{"Titan", "Saturn", "moons", 1.3452e23, 2_574.7 * km, 1_221_870 * km, 0.0288, 27.71, 169.1, 180.53, 163.31, 0},
+	{"Iapetus", "Saturn", "moons", 1.806e21, 734.5 * km, 3_560_820 * km, 0.0286, 17.28, 139.7, 271.61, 201.79, 0},
+	{"Uranus", "Sol", "outer", 8.6810e25, 25_362 * km, 19.18916464 * au, 0.04725744, 0.77263783, 74.01692503, 96.93735127, 142.28382821, -17.24},
+	{"Titania", "Uranus", "moons", 3.4e21, 788.4 * km, 435_910 * km, 0.0011, 97.77, 167.6, 284.4, 24.61, 0},
+	{"Oberon", "Uranus", "moons", 3.076e21, 761.4 * km, 583_520 * km, 0.0014, 97.86, 167.7, 104.4, 283.09, 0},
+	{"Neptune", "Sol", "outer", 1.02413e26, 24_622 * km, 30.06992276 * au, 0.00859048, 1.77004347, 131.78422574, 273.18053653, 259.91520804, 16.11},
+	{"Triton", "Neptune", "moons", 2.139e22, 1_353.4 * km, 354_759 * km, 0.000016, 130.06, 177.6, 344.05, 264.78, 0},
+	{"Pluto", "Sol", "pluto", 1.303e22, 1_188.3 * km, 39.48211675 * au, 0.24882730, 17.14001206, 110.30393684, 113.76497945, 14.86012204, -153.29},
+	{"Charon", "Pluto", "pluto", 1.586e21, 606 * km, 19_591 * km, 0.0002, 112.9, 227.4, 146.1, 131.0, 0},
+	{"Ceres", "Sol", "asteroids", 9.3835e20, 469.7 * km, 2.7675 * au, 0.0758, 10.593, 80.33, 73.6, 6.07, 9.074},
+	{"Pallas", "Sol", "asteroids", 2.04e20, 256 * km, 2.7724 * au, 0.2302, 34.84, 173.08, 310.05, 352.97, 7.813},
+	{"Juno", "Sol", "asteroids", 2.67e19, 123.3 * km, 2.6700 * au, 0.2562, 12.99, 169.85, 248.2, 32.98, 7.21},
+	{"Vesta", "Sol", "asteroids", 2.59076e20, 262.7 * km, 2.3615 * au, 0.0887, 7.14, 103.85, 151.2, 341.35, 5.342},
+	{"Hygiea", "Sol", "asteroids", 8.32e19, 216.5 * km, 3.1415 * au, 0.1125, 3.83, 283.2, 312.3, 193.14, 13.83},
+}
+
+// The sprite of a solar system body, found by its name in lower case; the
+// Sun is named Sol
+func solarSprite(name string) (*pixel.Sprite, bool) {
+	if name == "Sol" {
+		name = "sun"
+	}
+	sprite, ok := sprites[strings.ToLower(name)]
+	return sprite, ok
+}
+
+func (sb solarBody) elements() body.Elements {
+	rad := math.Pi / 180
+	return body.Elements{A: sb.a, E: sb.e, I: sb.i * rad, Node: sb.node * rad, Peri: sb.peri * rad, M: sb.m * rad}
+}
+
+// Resolve a comma separated selection of groups (all, planets, inner,
+// outer, moons, pluto, asteroids) and body names into the set of bodies to
+// build. Moons are only included with their planet, and naming a moon
+// brings its planet along.
+func selectSolarBodies(selection string) (map[string]bool, error) {
+	selected := map[string]bool{"Sol": true}
+	moons := false
+	for _, token := range strings.Split(selection, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		found := false
+		for _, sb := range solarBodies {
+			match := false
+			switch token {
+			case "all":
+				match = true
+			case "planets":
+				match = sb.group == "inner" || sb.group == "outer"
+			case "moons":
+				moons, found = true, true
+			default:
+				match = token == sb.group || token == strings.ToLower(sb.name)
+			}
+			if match {
+				found = true
+				for name := sb.name; name != ""; name = solarParent(name) {
+					selected[name] = true
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown solar system body or group %q", token)
+		}
+	}
+	if moons {
+		for _, sb := range solarBodies {
+			if sb.group == "moons" && selected[sb.parent] {
+				selected[sb.name] = true
+			}
+		}
+	}
+	return selected, nil
+}
+
+func solarParent(name string) string {
+	for _, sb := range solarBodies {
+		if sb.name == name {
+			return sb.parent
+		}
+	}
+	return ""
+}
+
+// The solar system at J2000 from orbital elements, in 3D, with the bodies
+// picked by selection. The timestep is set from the fastest orbit.
+func solarSystem(w, h int, selection string, rng *math_rand.Rand) (*World, error) {
+	selected, err := selectSolarBodies(selection)
+	if err != nil {
+		return nil, err
+	}
+	world := &World{
+		scale:   1.0,
+		spt:     100,
+		running: true,
+		elapsed: 0,
+		width:   w,
+		height:  h,
+		mag:     1.0,
+		epoch:   j2000,
+	}
+	byName := make(map[string]*body.Body)
+	shortest := math.Inf(1)
+	for _, sb := range solarBodies {
+		if !selected[sb.name] {
+			continue
+		}
+		sprite, ok := solarSprite(sb.name)
+		if !ok {
+			sprite = randomPlanetSprite(rng)
+		}
+		var b *body.Body
+		if parent := byName[sb.parent]; parent != nil {
+			el := sb.elements()
+			b = body.NewOrbitingBody(sb.name, parent, el, sb.radius, sb.mass, sprite)
+			period := el.Period(G * (parent.Mass + b.Mass))
+			shortest = math.Min(shortest, period)
+			if sb.spin == 0 {
+				b.SetSpinPeriod(period)
+			}
+		} else {
+			b = body.NewBody(sb.name, 0, 0, sb.radius, sb.mass, 0, 0, sprite)
+		}
+		if sb.spin != 0 {
+			b.SetSpinPeriod(sb.spin * 3600)
+		}
+		byName[sb.name] = b
+		world.bodies = append(world.bodies, b)
+	}
+
+	// The elements of a planet are those of its system barycenter, so shift
+	// the planet and its moons to keep the barycenter on that orbit
+	for _, planet := range world.bodies {
+		system := []*body.Body{planet}
+		for _, sb := range solarBodies {
+			if sb.parent == planet.Name && byName[sb.name] != nil && planet.Name != "Sol" {
+				system = append(system, byName[sb.name])
+			}
+		}
+		if len(system) == 1 {
+			continue
+		}
+		var mass float64
+		var pos, vel vector.Vector
+		for _, b := range system {
+			mass += b.Mass
+			pos.Add(vector.MultScalar(b.Pos, b.Mass))
+			vel.Add(vector.MultScalar(b.Vel, b.Mass))
+		}
+		pos = vector.Sub(vector.DivScalar(pos, mass), planet.Pos)
+		vel = vector.Sub(vector.DivScalar(vel, mass), planet.Vel)
+		for _, b := range system {
+			b.Pos.Sub(pos)
+			b.Vel.Sub(vel)
+		}
+	}
+
+	if !math.IsInf(shortest, 1) {
+		world.dt = shortest / 2000
+	}
+	world.fitToScreen()
+	for _, b := range world.bodies {
+		fmt.Printf("%v\n", b)
+	}
+	return world, nil
+}
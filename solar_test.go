@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/seifertd/nbody-go/horizons"
+	"strings"
+	"testing"
+)
+
+func TestSolarSystem(t *testing.T) {
+	resetSprites("sun")
+	world, err := solarSystem(1024, 1024, "inner,moons", newRand(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if world.bodies[0].Sprite != sprites["sun"] {
+		t.Errorf("Sol should be drawn with the sun sprite")
+	}
+	var names []string
+	for _, b := range world.bodies {
+		names = append(names, b.Name)
+	}
+	if strings.Join(names, " ") != "Sol Mercury Venus Earth Luna Mars Phobos Deimos" {
+		t.Errorf("unexpected bodies: %v", names)
+	}
+	// Compare with the Horizons heliocentric positions at J2000
+	for i, path := range []string{"horizons/testdata/earth.txt", "horizons/testdata/mars-csv.txt"} {
+		v, err := horizons.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := world.bodies[[]int{3, 5}[i]]
+		if d := b.Pos.DistanceTo(v.Records[0].Pos); d > 1e-3*au {
+			t.Errorf("%v is %.4g km from Horizons", b.Name, d/km)
+		}
+	}
+
+	if world, _ := solarSystem(1024, 1024, "Titan", newRand(1)); len(world.bodies) != 3 {
+		t.Errorf("a moon should bring its planet: %v", world.bodies)
+	}
+	if _, err := solarSystem(1024, 1024, "outer,vulcan", newRand(1)); err == nil {
+		t.Errorf("unknown bodies should be an error")
+	}
+}
@@ -0,0 +1,587 @@
+// Package trajectory records the positions and velocities of the bodies of
+// a run to a compact binary file, and reads them back streaming or seeking
+// by time.
+//
+// A file starts with the magic "NBODYTRJ", a little endian uint32 length
+// and that many bytes of JSON Header describing the rest. Blocks follow,
+// each a kind byte, a uint32 payload length and the payload, all little
+// endian:
+//
+//	'A' a body joined: time f64, id u64, mass f64, radius f64, flags u8, name
+//	'R' a body left:   time f64, id u64, kind u8, into u64
+//	'C' frames of the same bodies:
+//	    start f64, end f64, bodies u32, frames u32,
+//	    per body: id u64, mass f64, radius f64,
+//	    per frame: time f64, per body x, y, z, vx, vy, vz as floats of the
+//	    header's size
+//
+// A chunk closes whenever the bodies or their masses change, so events sit
+// between the chunks in time order and a reader seeks by skipping whole
+// chunks.
+package trajectory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+const (
+	magic   = "NBODYTRJ"
+	Version = 1
+	// Chunks are closed once their frames take this many bytes, bounding
+	// what a reader holds in memory
+	maxChunkBytes = 1 << 20
+)
+
+// Header describes a trajectory file
+type Header struct {
+	Version int `json:"version"`
+	// bytes per coordinate, 4 or 8
+	FloatSize int               `json:"float_size"`
+	Fields    []string          `json:"fields"`
+	Units     map[string]string `json:"units"`
+	// simulated seconds between frames
+	Every       float64 `json:"every,omitempty"`
+	Seed        int64   `json:"seed,omitempty"`
+	Description string  `json:"description,omitempty"`
+	// values the recording program wants back, like the view it showed
+	Attributes map[string]float64 `json:"attributes,omitempty"`
+}
+
+// EventKind is what happened to a body
+type EventKind uint8
+
+const (
+	Added EventKind = iota
+	Merged
+	Escaped
+)
+
+func (k EventKind) String() string {
+	return [...]string{"added", "merged", "escaped"}[k]
+}
+
+// Event is a body joining or leaving the recording. Name, mass, radius and
+// test particle are given for Added, Into for Merged.
+type Event struct {
+	Time         float64
+	Kind         EventKind
+	Id           uint64
+	Name         string
+	Mass         float64
+	Radius       float64
+	TestParticle bool
+	Into         uint64
+}
+
+// State of one body in a frame
+type State struct {
+	Id     uint64
+	Mass   float64
+	Radius float64
+	Pos    vector.Vector
+	Vel    vector.Vector
+}
+
+// Frame is the state of every body at one time
+type Frame struct {
+	Time   float64
+	Bodies []State
+}
+
+// Writer records a trajectory
+type Writer struct {
+	out       *bufio.Writer
+	file      *os.File
+	floatSize int
+	// bodies of the open chunk and its encoded frames
+	roster []State
+	frames bytes.Buffer
+	count  int
+	start  float64
+	end    float64
+	buf    []byte
+}
+
+// NewWriter writes the header to out and returns a Writer for the blocks.
+// The header's version, fields and units are filled in; float size defaults
+// to 8.
+func NewWriter(out io.Writer, h Header) (*Writer, error) {
+	if h.FloatSize == 0 {
+		h.FloatSize = 8
+	}
+	if h.FloatSize != 4 && h.FloatSize != 8 {
+		return nil, fmt.Errorf("float size must be 4 or 8, not %v", h.FloatSize)
+	}
+	h.Version = Version
+	h.Fields = []string{"x", "y", "z", "vx", "vy", "vz"}
+	h.Units = map[string]string{"time": "s", "length": "m", "velocity": "m/s", "mass": "kg"}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{out: bufio.NewWriterSize(out, 1<<16), floatSize: h.FloatSize}
+	w.out.WriteString(magic)
+	binary.Write(w.out, binary.LittleEndian, uint32(len(data)))
+	if _, err := w.out.Write(data); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Create a trajectory file at path
+func Create(path string, h Header) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(file, h)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.file = file
+	return w, nil
+}
+
+func (w *Writer) block(kind byte, payload []byte) error {
+	w.out.WriteByte(kind)
+	binary.Write(w.out, binary.LittleEndian, uint32(len(payload)))
+	_, err := w.out.Write(payload)
+	return err
+}
+
+func putFloat(b []byte, f float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
+
+// Add records a body joining at time t
+func (w *Writer) Add(t float64, id uint64, name string, mass, radius float64, testParticle bool) error {
+	if err := w.closeChunk(); err != nil {
+		return err
+	}
+	b := putFloat(w.buf[:0], t)
+	b = binary.LittleEndian.AppendUint64(b, id)
+	b = putFloat(putFloat(b, mass), radius)
+	flags := byte(0)
+	if testParticle {
+		flags = 1
+	}
+	b = append(append(b, flags), name...)
+	w.buf = b
+	return w.block('A', b)
+}
+
+// Remove records a body leaving at time t, merged into another body or
+// escaped
+func (w *Writer) Remove(t float64, id uint64, kind EventKind, into uint64) error {
+	if err := w.closeChunk(); err != nil {
+		return err
+	}
+	b := putFloat(w.buf[:0], t)
+	b = binary.LittleEndian.AppendUint64(b, id)
+	b = binary.LittleEndian.AppendUint64(append(b, byte(kind)), into)
+	w.buf = b
+	return w.block('R', b)
+}
+
+// Same bodies with the same masses and radii as the open chunk
+func (w *Writer) sameRoster(bodies []State) bool {
+	if len(bodies) != len(w.roster) {
+		return false
+	}
+	for i, b := range bodies {
+		r := w.roster[i]
+		if b.Id != r.Id || b.Mass != r.Mass || b.Radius != r.Radius {
+			return false
+		}
+	}
+	return true
+}
+
+// Frame records the state of the bodies at time t
+func (w *Writer) Frame(t float64, bodies []State) error {
+	if w.count > 0 && (!w.sameRoster(bodies) || w.frames.Len() >= maxChunkBytes) {
+		if err := w.closeChunk(); err != nil {
+			return err
+		}
+	}
+	if w.count == 0 {
+		w.roster = append(w.roster[:0], bodies...)
+		w.start = t
+	}
+	b := putFloat(w.buf[:0], t)
+	for _, s := range bodies {
+		for _, f := range [6]float64{s.Pos.X, s.Pos.Y, s.Pos.Z, s.Vel.X, s.Vel.Y, s.Vel.Z} {
+			if w.floatSize == 4 {
+				b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(f)))
+			} else {
+				b = putFloat(b, f)
+			}
+		}
+	}
+	w.buf = b
+	w.frames.Write(b)
+	w.count++
+	w.end = t
+	return nil
+}
+
+// Write the open chunk
+func (w *Writer) closeChunk() error {
+	if w.count == 0 {
+		return nil
+	}
+	b := putFloat(putFloat(nil, w.start), w.end)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(w.roster)))
+	b = binary.LittleEndian.AppendUint32(b, uint32(w.count))
+	for _, s := range w.roster {
+		b = binary.LittleEndian.AppendUint64(b, s.Id)
+		b = putFloat(putFloat(b, s.Mass), s.Radius)
+	}
+	w.out.WriteByte('C')
+	binary.Write(w.out, binary.LittleEndian, uint32(len(b)+w.frames.Len()))
+	w.out.Write(b)
+	_, err := w.frames.WriteTo(w.out)
+	w.count = 0
+	return err
+}
+
+// Flush writes everything recorded so far
+func (w *Writer) Flush() error {
+	if err := w.closeChunk(); err != nil {
+		return err
+	}
+	return w.out.Flush()
+}
+
+// Close flushes the writer and closes the file it was created with
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Where a chunk is in the file, for seeking
+type chunkIndex struct {
+	offset int64
+	start  float64
+	end    float64
+}
+
+// Reader reads a trajectory
+type Reader struct {
+	in     io.Reader
+	file   *os.File
+	header Header
+	// offset of the first block
+	data   int64
+	events []Event
+	added  map[uint64]Event
+	// events up to this offset are in events
+	seen  int64
+	pos   int64
+	index []chunkIndex
+	// bodies of the current chunk and how many of its frames are left
+	roster  []State
+	left    int
+	pending *Frame
+	buf     []byte
+}
+
+// NewReader reads the header from in. Seek needs in to be an io.ReadSeeker
+// positioned at the start of the trajectory.
+func NewReader(in io.Reader) (*Reader, error) {
+	r := &Reader{in: in, added: map[uint64]Event{}}
+	head := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(in, head); err != nil {
+		return nil, fmt.Errorf("trajectory header: %v", err)
+	}
+	if string(head[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not a trajectory file")
+	}
+	data := make([]byte, binary.LittleEndian.Uint32(head[len(magic):]))
+	if _, err := io.ReadFull(in, data); err != nil {
+		return nil, fmt.Errorf("trajectory header: %v", err)
+	}
+	if err := json.Unmarshal(data, &r.header); err != nil {
+		return nil, fmt.Errorf("trajectory header: %v", err)
+	}
+	if r.header.Version != Version {
+		return nil, fmt.Errorf("trajectory version %v, expected %v", r.header.Version, Version)
+	}
+	if r.header.FloatSize != 4 && r.header.FloatSize != 8 {
+		return nil, fmt.Errorf("trajectory float size %v", r.header.FloatSize)
+	}
+	r.data = int64(len(head) + len(data))
+	r.pos, r.seen = r.data, r.data
+	return r, nil
+}
+
+// Open the trajectory file at path
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	r.file = file
+	return r, nil
+}
+
+func (r *Reader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
+
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Events read so far in time order, all of them once Seek or Span has been
+// called
+func (r *Reader) Events() []Event {
+	return r.events
+}
+
+// Body gives the event that added the body with id, once it has been read
+func (r *Reader) Body(id uint64) (Event, bool) {
+	e, ok := r.added[id]
+	return e, ok
+}
+
+// Read n bytes of a block
+func (r *Reader) read(n int) ([]byte, error) {
+	b, err := r.readHead(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+// Read n bytes starting a block, io.EOF at the end of the file
+func (r *Reader) readHead(n int) ([]byte, error) {
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	b := r.buf[:n]
+	if _, err := io.ReadFull(r.in, b); err != nil {
+		return nil, err
+	}
+	r.pos += int64(n)
+	return b, nil
+}
+
+func getFloat(b []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+// Decode an event block's payload, keeping it unless it was read before
+func (r *Reader) event(kind byte, b []byte) error {
+	if len(b) < 17 || (kind == 'A' && len(b) < 33) || (kind == 'R' && len(b) < 25) {
+		return fmt.Errorf("short %c block", kind)
+	}
+	e := Event{Time: getFloat(b), Id: binary.LittleEndian.Uint64(b[8:])}
+	if kind == 'A' {
+		e.Kind, e.Mass, e.Radius = Added, getFloat(b[16:]), getFloat(b[24:])
+		e.TestParticle, e.Name = b[32]&1 != 0, string(b[33:])
+		r.added[e.Id] = e
+	} else {
+		e.Kind, e.Into = EventKind(b[16]), binary.LittleEndian.Uint64(b[17:])
+	}
+	if r.pos > r.seen {
+		r.events = append(r.events, e)
+		r.seen = r.pos
+	}
+	return nil
+}
+
+// Read block headers up to the next chunk and its roster
+func (r *Reader) nextChunk() error {
+	for {
+		head, err := r.readHead(5)
+		if err != nil {
+			return err
+		}
+		kind, size := head[0], int(binary.LittleEndian.Uint32(head[1:]))
+		if kind != 'C' {
+			b, err := r.read(size)
+			if err != nil {
+				return err
+			}
+			if err := r.event(kind, b); err != nil {
+				return err
+			}
+			continue
+		}
+		b, err := r.read(24)
+		if err != nil {
+			return err
+		}
+		n, frames := int(binary.LittleEndian.Uint32(b[16:])), int(binary.LittleEndian.Uint32(b[20:]))
+		if r.pos-24+int64(size) > r.seen {
+			r.seen = r.pos - 24 + int64(size)
+		}
+		b, err = r.read(24 * n)
+		if err != nil {
+			return err
+		}
+		r.roster = r.roster[:0]
+		for i := 0; i < n; i++ {
+			s := b[24*i:]
+			r.roster = append(r.roster, State{Id: binary.LittleEndian.Uint64(s), Mass: getFloat(s[8:]),
+				Radius: getFloat(s[16:])})
+		}
+		r.left = frames
+		return nil
+	}
+}
+
+// Next frame, io.EOF after the last one
+func (r *Reader) Next() (Frame, error) {
+	if r.pending != nil {
+		f := *r.pending
+		r.pending = nil
+		return f, nil
+	}
+	for r.left == 0 {
+		if err := r.nextChunk(); err != nil {
+			return Frame{}, err
+		}
+	}
+	fs := r.header.FloatSize
+	b, err := r.read(8 + 6*fs*len(r.roster))
+	if err != nil {
+		return Frame{}, err
+	}
+	r.left--
+	f := Frame{Time: getFloat(b), Bodies: make([]State, len(r.roster))}
+	b = b[8:]
+	for i, s := range r.roster {
+		var c [6]float64
+		for j := range c {
+			if fs == 4 {
+				c[j] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+			} else {
+				c[j] = getFloat(b)
+			}
+			b = b[fs:]
+		}
+		s.Pos, s.Vel = vector.Vector{c[0], c[1], c[2]}, vector.Vector{c[3], c[4], c[5]}
+		f.Bodies[i] = s
+	}
+	return f, nil
+}
+
+// Scan the block headers of the whole file for its chunks and events
+func (r *Reader) buildIndex() error {
+	if r.index != nil {
+		return nil
+	}
+	seeker, ok := r.in.(io.ReadSeeker)
+	if !ok {
+		return fmt.Errorf("trajectory is not seekable")
+	}
+	resume := r.pos
+	r.pos = r.data
+	if _, err := seeker.Seek(r.data, io.SeekStart); err != nil {
+		return err
+	}
+	index := []chunkIndex{}
+	for {
+		offset := r.pos
+		head, err := r.readHead(5)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		kind, size := head[0], int64(binary.LittleEndian.Uint32(head[1:]))
+		if kind != 'C' {
+			b, err := r.read(int(size))
+			if err != nil {
+				return err
+			}
+			if err := r.event(kind, b); err != nil {
+				return err
+			}
+			continue
+		}
+		b, err := r.read(16)
+		if err != nil {
+			return err
+		}
+		index = append(index, chunkIndex{offset, getFloat(b), getFloat(b[8:])})
+		if r.pos+size-16 > r.seen {
+			r.seen = r.pos + size - 16
+		}
+		if r.pos, err = seeker.Seek(size-16, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+	r.index = index
+	r.pos = resume
+	_, err := seeker.Seek(resume, io.SeekStart)
+	return err
+}
+
+// Span of the recording, the times of its first and last frames
+func (r *Reader) Span() (float64, float64, error) {
+	if err := r.buildIndex(); err != nil {
+		return 0, 0, err
+	}
+	if len(r.index) == 0 {
+		return 0, 0, nil
+	}
+	return r.index[0].start, r.index[len(r.index)-1].end, nil
+}
+
+// Seek so Next returns the first frame at or after t
+func (r *Reader) Seek(t float64) error {
+	if err := r.buildIndex(); err != nil {
+		return err
+	}
+	i := sort.Search(len(r.index), func(i int) bool { return r.index[i].end >= t })
+	offset := r.pos
+	if i < len(r.index) {
+		offset = r.index[i].offset
+	} else if len(r.index) > 0 {
+		offset = r.seen
+	}
+	if _, err := r.in.(io.ReadSeeker).Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.pos, r.left, r.pending = offset, 0, nil
+	for {
+		f, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if f.Time >= t {
+			r.pending = &f
+			return nil
+		}
+	}
+}
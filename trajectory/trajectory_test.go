@@ -0,0 +1,133 @@
+package trajectory
+
+import (
+	"bytes"
+	"github.com/seifertd/go/vector"
+	"io"
+	"math"
+	"testing"
+)
+
+// Two bodies recorded for 300 frames, the second merging into the first
+// at t = 100
+func record(t *testing.T, floatSize int) []byte {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, Header{FloatSize: floatSize, Every: 1, Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Add(0, 1, "Sol", 2e30, 7e8, false)
+	w.Add(0, 2, "Earth", 6e24, 6.4e6, false)
+	for i := 0; i < 300; i++ {
+		time := float64(i)
+		bodies := []State{{Id: 1, Mass: 2e30, Radius: 7e8, Pos: vector.Vector{time, 0, 0}}}
+		if i < 100 {
+			bodies = append(bodies, State{Id: 2, Mass: 6e24, Radius: 6.4e6, Pos: vector.Vector{1.5e11, time, 0.1},
+				Vel: vector.Vector{0, 3e4, 0}})
+		} else if i == 100 {
+			w.Remove(time, 2, Merged, 1)
+			bodies[0].Mass += 6e24
+		} else {
+			bodies[0].Mass += 6e24
+		}
+		if err := w.Frame(time, bodies); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestStream(t *testing.T) {
+	for _, size := range []int{4, 8} {
+		data := record(t, size)
+		// Read from a plain io.Reader, nothing seekable
+		r, err := NewReader(io.MultiReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h := r.Header(); h.Version != Version || h.FloatSize != size || h.Seed != 42 || len(h.Fields) != 6 {
+			t.Errorf("wrong header %+v", h)
+		}
+		frames := 0
+		for {
+			f, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Time != float64(frames) || f.Bodies[0].Pos.X != f.Time {
+				t.Fatalf("frame %v has time %v", frames, f.Time)
+			}
+			if frames >= 100 {
+				if len(f.Bodies) != 1 || f.Bodies[0].Mass != 2e30+6e24 {
+					t.Fatalf("frame %v should have the merged body alone: %+v", frames, f.Bodies)
+				}
+			} else if len(f.Bodies) != 2 || math.Abs(f.Bodies[1].Pos.X-1.5e11) > 1.5e11*1e-7 ||
+				f.Bodies[1].Vel.Y != 3e4 {
+				t.Fatalf("frame %v has wrong bodies: %+v", frames, f.Bodies)
+			}
+			frames++
+		}
+		if frames != 300 {
+			t.Errorf("read %v frames, expected 300", frames)
+		}
+		events := r.Events()
+		if len(events) != 3 || events[2].Kind != Merged || events[2].Id != 2 || events[2].Into != 1 {
+			t.Errorf("wrong events %+v", events)
+		}
+		if e, ok := r.Body(2); !ok || e.Name != "Earth" || e.Mass != 6e24 {
+			t.Errorf("wrong body 2: %+v", e)
+		}
+		if err := r.Seek(10); err == nil {
+			t.Errorf("seeking a stream should be an error")
+		}
+	}
+	if len(record(t, 4)) >= len(record(t, 8)) {
+		t.Errorf("single precision should be smaller")
+	}
+}
+
+func TestSeek(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(record(t, 8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := r.Next()
+	start, end, err := r.Span()
+	if err != nil || start != 0 || end != 299 {
+		t.Errorf("span %v to %v: %v", start, end, err)
+	}
+	// Seeking reads every event, once
+	if len(r.Events()) != 3 {
+		t.Errorf("expected 3 events after indexing, got %v", len(r.Events()))
+	}
+	for _, seek := range []float64{250, 99.5, 0, 299} {
+		if err := r.Seek(seek); err != nil {
+			t.Fatal(err)
+		}
+		f, err := r.Next()
+		if err != nil || f.Time != math.Ceil(seek) {
+			t.Errorf("seek to %v gave frame at %v: %v", seek, f.Time, err)
+		}
+		if f, _ := r.Next(); seek < 299 && f.Time != math.Ceil(seek)+1 {
+			t.Errorf("frame after seeking to %v at %v", seek, f.Time)
+		}
+	}
+	if first.Time != 0 || len(r.Events()) != 3 {
+		t.Errorf("events read twice: %v", len(r.Events()))
+	}
+	if err := r.Seek(1000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("seeking past the end should give io.EOF, got %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader([]byte("not a trajectory"))); err == nil {
+		t.Errorf("garbage should be refused")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"github.com/seifertd/go/vector"
+	"github.com/seifertd/nbody-go/body"
+	"math"
+	math_rand "math/rand"
+	"strings"
+)
+
+// Position of the Lagrange points of a circular two body orbit in the
+// rotating frame, in units of the separation with the barycenter at the
+// origin, the primary at (-mu, 0) and the secondary at (1-mu, 0), where mu
+// is the secondary's fraction of the mass.
+func lagrangePoint(name string, mu float64) (float64, float64, error) {
+	// Net acceleration along the line in the rotating frame
+	f := func(x float64) float64 {
+		r1, r2 := x+mu, x-1+mu
+		return x - (1-mu)*r1/math.Pow(math.Abs(r1), 3) - mu*r2/math.Pow(math.Abs(r2), 3)
+	}
+	// f rises from -Inf to +Inf across each of these intervals, and
+	// crosses zero once
+	bisect := func(lo, hi float64) float64 {
+		for i := 0; i < 200; i++ {
+			mid := (lo + hi) / 2
+			if f(mid) < 0 {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return (lo + hi) / 2
+	}
+	const eps = 1e-12
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "L1":
+		return bisect(-mu+eps, 1-mu-eps), 0, nil
+	case "L2":
+		return bisect(1-mu+eps, 2), 0, nil
+	case "L3":
+		return bisect(-2, -mu-eps), 0, nil
+	case "L4":
+		return 0.5 - mu, math.Sqrt(3) / 2, nil
+	case "L5":
+		return 0.5 - mu, -math.Sqrt(3) / 2, nil
+	}
+	return 0, 0, fmt.Errorf("unknown Lagrange point %q, one of L1, L2, L3, L4, L5", name)
+}
+
+// The Sun and a planet from the solar system table on a circular orbit,
+// with n test particles scattered around the given Lagrange points. The
+// particles move with the rotating frame; spread is the standard deviation
+// of their angle around the Sun in radians, and a tenth of it that of their
+// distance relative to the point's.
+func trojanSwarm(w, h int, planet string, points []string, n int, spread float64, rng *math_rand.Rand) (*World, error) {
+	var sb *solarBody
+	for i := range solarBodies {
+		if strings.EqualFold(solarBodies[i].name, planet) && solarBodies[i].parent == "Sol" {
+			sb = &solarBodies[i]
+		}
+	}
+	if sb == nil {
+		return nil, fmt.Errorf("%q is not a body orbiting the Sun", planet)
+	}
+	world := &World{
+		scale:    1.0,
+		spt:      10,
+		running:  true,
+		elapsed:  0,
+		width:    w,
+		height:   h,
+		mag:      1.0,
+		corotate: true,
+	}
+	sun := body.NewBody("Sol", 0, 0, solarBodies[0].radius, solarBodies[0].mass, 0, 0, sprites["sun"])
+	sprite, ok := solarSprite(sb.name)
+	if !ok {
+		sprite = randomPlanetSprite(rng)
+	}
+	el := body.Elements{A: sb.a}
+	p := body.NewOrbitingBody(sb.name, sun, el, sb.radius, sb.mass, sprite)
+	world.bodies = []*body.Body{sun, p}
+	world.fitToScreen()
+
+	m := sun.Mass + p.Mass
+	mu := p.Mass / m
+	omega := 2 * math.Pi / el.Period(G*m)
+	bary := vector.DivScalar(vector.Add(vector.MultScalar(sun.Pos, sun.Mass), vector.MultScalar(p.Pos, p.Mass)), m)
+	baryVel := vector.DivScalar(vector.Add(vector.MultScalar(sun.Vel, sun.Mass), vector.MultScalar(p.Vel, p.Mass)), m)
+	for i := 0; i < n; i++ {
+		name := points[i%len(points)]
+		x, y, err := lagrangePoint(name, mu)
+		if err != nil {
+			return nil, err
+		}
+		// Scatter in polar coordinates about the barycenter, the planet is on
+		// the x axis of the rotating frame at the start
+		r := math.Hypot(x, y) * sb.a * (1 + rng.NormFloat64()*spread/10)
+		theta := math.Atan2(y, x) + rng.NormFloat64()*spread
+		offset := vector.Vector{r * math.Cos(theta), r * math.Sin(theta), 0}
+		vel := vector.Vector{-omega * offset.Y, omega * offset.X, 0}
+		world.bodies = append(world.bodies, body.NewTestParticle(fmt.Sprintf("%v-%v", strings.ToUpper(strings.TrimSpace(name)), i),
+			vector.Add(bary, offset), vector.Add(baryVel, vel), MinRadius*world.mpp, sprites["circle"]))
+	}
+
+	world.dt = el.Period(G*m) / 2000
+	world.fitToScreen()
+	fmt.Printf("%v\n%v\n", sun, p)
+	fmt.Printf("%v test particles around %v of the Sun-%v pair, mass ratio %.4g\n",
+		n, strings.Join(points, ", "), sb.name, mu)
+	return world, nil
+}
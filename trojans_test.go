@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLagrangePoints(t *testing.T) {
+	mu := 9.537e-4
+	// Sun-Jupiter collinear points
+	for name, want := range map[string]float64{"L1": 0.93236, "L2": 1.06883, "L3": -1.00040} {
+		if x, _, _ := lagrangePoint(name, mu); math.Abs(x-want) > 1e-4 {
+			t.Errorf("%v at %v, expected %v", name, x, want)
+		}
+	}
+	if _, _, err := lagrangePoint("L6", mu); err == nil {
+		t.Errorf("unknown points should be an error")
+	}
+
+	// Without scatter a Trojan stays at L4 in the co-rotating frame
+	resetSprites()
+	world, err := trojanSwarm(1024, 1024, "Jupiter", []string{"L4"}, 1, 0, newRand(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2000; i++ {
+		world.tick()
+	}
+	world.updateFrame()
+	sun, jupiter, trojan := world.bodies[0], world.bodies[1], world.bodies[2]
+	a := sun.Pos.DistanceTo(jupiter.Pos)
+	if d := math.Abs(trojan.Pos.DistanceTo(sun.Pos)/a - 1); d > 1e-3 {
+		t.Errorf("trojan drifted from L4 by %v", d)
+	}
+	if d := math.Abs(trojan.Pos.DistanceTo(jupiter.Pos)/a - 1); d > 1e-3 {
+		t.Errorf("trojan drifted from L4 by %v", d)
+	}
+	s, j, tr := world.worldToScreen(&sun.Pos), world.worldToScreen(&jupiter.Pos), world.worldToScreen(&trojan.Pos)
+	if math.Abs(s.Y-j.Y) > 1e-6 || j.X < s.X || tr.Y < s.Y {
+		t.Errorf("co-rotating view should keep Jupiter right of the Sun and L4 above: %v %v %v", s, j, tr)
+	}
+}